@@ -0,0 +1,75 @@
+package main
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/mrityunjaygr8/greenlight/internal/data"
+	"github.com/mrityunjaygr8/greenlight/internal/validator"
+)
+
+func (app *application) recordAuditEvent(r *http.Request, action string, success bool, userID int64, details map[string]string) {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+
+	event := &data.AuditEvent{
+		UserID:    userID,
+		Action:    action,
+		Success:   success,
+		IPAddress: ip,
+		Details:   details,
+	}
+
+	app.background(func() {
+		err := app.models.Audit.Insert(event)
+		if err != nil {
+			app.logger.PrintError(err, map[string]string{
+				"audit_action": action,
+			})
+		}
+	})
+}
+
+func (app *application) listAuditEventsHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		UserID int64
+		Action string
+		data.Filters
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	input.UserID = int64(app.readInt(qs, "user_id", 0, v))
+	input.Action = app.readString(qs, "action", "")
+
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+
+	input.Filters.Sort = app.readString(qs, "sort", "-created_at")
+	input.Filters.SortSafelist = []string{"id", "created_at", "action", "-id", "-created_at", "-action"}
+
+	v.Check(input.UserID >= 0, "user_id", "must not be negative")
+	if input.Action != "" {
+		v.Check(validator.In(input.Action, data.AuditActions...), "action", "invalid action value")
+	}
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	events, metadata, err := app.models.Audit.GetAll(input.UserID, input.Action, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"events": events, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
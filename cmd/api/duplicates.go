@@ -0,0 +1,93 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/mrityunjaygr8/greenlight/internal/data"
+	"github.com/mrityunjaygr8/greenlight/internal/validator"
+)
+
+func (app *application) listDuplicateMoviesHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		data.DuplicateCriteria
+		data.Filters
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	input.DuplicateCriteria.MovieID = int64(app.readInt(qs, "movie_id", 0, v))
+	input.DuplicateCriteria.MinSimilarity = app.readFloat(qs, "min_similarity", 0.8, v)
+	input.DuplicateCriteria.RuntimeTolerance = int32(app.readInt(qs, "runtime_tolerance", 5, v))
+
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+
+	// Candidates always come most similar first.
+	input.Filters.Sort = "similarity"
+	input.Filters.SortSafelist = []string{"similarity"}
+
+	data.ValidateDuplicateCriteria(v, input.DuplicateCriteria)
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	candidates, metadata, err := app.models.Movies.FindDuplicates(input.DuplicateCriteria, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"duplicates": candidates, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) mergeMovieHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		TargetID int64 `json:"target_id"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	v.Check(input.TargetID > 0, "target_id", "must be provided")
+	v.Check(input.TargetID != id, "target_id", "must be a different movie")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	movie, err := app.models.Movies.Merge(id, input.TargetID, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"movie": movie}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,369 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mrityunjaygr8/greenlight/internal/data"
+	"github.com/mrityunjaygr8/greenlight/internal/validator"
+)
+
+// exportBatchDeadline is how long the client has to take each batch of an
+// export. The write deadline is pushed back before every batch, so exports of
+// any size succeed as long as the client keeps reading.
+const exportBatchDeadline = time.Minute
+
+const (
+	exportFormatCSV    = "csv"
+	exportFormatNDJSON = "ndjson"
+)
+
+// exportColumns lists every exportable movie field in output order, with its
+// CSV and NDJSON forms. Runtimes are plain minutes in CSV but keep their
+// "<n> mins" form in NDJSON, and genres are joined by | in CSV, matching what
+// the import endpoint reads; an export with fields=title,year,runtime,genres
+// can be imported as is.
+var exportColumns = []struct {
+	name string
+	csv  func(movie *data.Movie) string
+	json func(movie *data.Movie) interface{}
+}{
+	{
+		"id",
+		func(movie *data.Movie) string { return strconv.FormatInt(movie.ID, 10) },
+		func(movie *data.Movie) interface{} { return movie.ID },
+	},
+	{
+		"title",
+		func(movie *data.Movie) string { return movie.Title },
+		func(movie *data.Movie) interface{} { return movie.Title },
+	},
+	{
+		"original_title",
+		func(movie *data.Movie) string { return movie.OriginalTitle },
+		func(movie *data.Movie) interface{} { return movie.OriginalTitle },
+	},
+	{
+		"language",
+		func(movie *data.Movie) string { return movie.Language },
+		func(movie *data.Movie) interface{} { return movie.Language },
+	},
+	{
+		"synopsis",
+		func(movie *data.Movie) string { return movie.Synopsis },
+		func(movie *data.Movie) interface{} { return movie.Synopsis },
+	},
+	{
+		"year",
+		func(movie *data.Movie) string { return strconv.FormatInt(int64(movie.Year), 10) },
+		func(movie *data.Movie) interface{} { return movie.Year },
+	},
+	{
+		"runtime",
+		func(movie *data.Movie) string { return strconv.FormatInt(int64(movie.Runtime), 10) },
+		func(movie *data.Movie) interface{} { return movie.Runtime },
+	},
+	{
+		"genres",
+		func(movie *data.Movie) string { return strings.Join(movie.Genres, "|") },
+		func(movie *data.Movie) interface{} {
+			if movie.Genres == nil {
+				return []string{}
+			}
+			return movie.Genres
+		},
+	},
+	{
+		"average_rating",
+		func(movie *data.Movie) string { return strconv.FormatFloat(movie.AverageRating, 'f', -1, 64) },
+		func(movie *data.Movie) interface{} { return movie.AverageRating },
+	},
+	{
+		"rating_count",
+		func(movie *data.Movie) string { return strconv.FormatInt(int64(movie.RatingCount), 10) },
+		func(movie *data.Movie) interface{} { return movie.RatingCount },
+	},
+	{
+		"in_watchlist",
+		func(movie *data.Movie) string {
+			return strconv.FormatBool(movie.InWatchlist != nil && *movie.InWatchlist)
+		},
+		func(movie *data.Movie) interface{} { return movie.InWatchlist != nil && *movie.InWatchlist },
+	},
+	{
+		"poster",
+		func(movie *data.Movie) string { return imageURL(movie.Poster) },
+		func(movie *data.Movie) interface{} { return movie.Poster },
+	},
+	{
+		"backdrop",
+		func(movie *data.Movie) string { return imageURL(movie.Backdrop) },
+		func(movie *data.Movie) interface{} { return movie.Backdrop },
+	},
+	{
+		"external_ids",
+		func(movie *data.Movie) string { return formatExternalIDs(movie.ExternalIDs) },
+		func(movie *data.Movie) interface{} {
+			if movie.ExternalIDs == nil {
+				return map[string]string{}
+			}
+			return movie.ExternalIDs
+		},
+	},
+	{
+		"version",
+		func(movie *data.Movie) string { return strconv.FormatInt(int64(movie.Version), 10) },
+		func(movie *data.Movie) interface{} { return movie.Version },
+	},
+}
+
+// imageURL returns the URL of the image, or an empty string for a movie
+// without one.
+func imageURL(image *data.MovieImage) string {
+	if image == nil {
+		return ""
+	}
+	return image.URL
+}
+
+// formatExternalIDs joins external IDs as source:id pairs separated by |,
+// sorted so that exports are stable.
+func formatExternalIDs(externalIDs map[string]string) string {
+	pairs := make([]string, 0, len(externalIDs))
+	for source, externalID := range externalIDs {
+		pairs = append(pairs, source+":"+externalID)
+	}
+
+	sort.Strings(pairs)
+	return strings.Join(pairs, "|")
+}
+
+// movieExporter writes movies in one export format.
+type movieExporter interface {
+	write(movies []*data.Movie) error
+	flush() error
+}
+
+func (app *application) exportMoviesHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		data.MovieFilters
+		data.Filters
+		Format    string
+		Fields    []string
+		Languages []string
+		Snapshot  bool
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	input.MovieFilters = app.readMovieFilters(qs, v)
+	input.Format = app.readString(qs, "format", exportFormatNDJSON)
+	input.Fields = app.readCSV(qs, "fields", []string{})
+	input.Languages = app.readLanguageParam(qs, v)
+	input.Snapshot = app.readBool(qs, "snapshot", false, v)
+
+	input.Filters.Sort = app.readString(qs, "sort", "id")
+	input.Filters.SortSafelist = []string{"id", "title", "year", "runtime", "average_rating", "rating_count", "-id", "-title", "-year", "-runtime", "-average_rating", "-rating_count"}
+
+	data.ValidateMovieFilters(v, input.MovieFilters)
+	data.ValidateMovieFields(v, input.Fields, nil)
+
+	v.Check(validator.In(input.Format, exportFormatCSV, exportFormatNDJSON), "format", "must be csv or ndjson")
+	v.Check(validator.In(input.Filters.Sort, input.Filters.SortSafelist...), "sort", "invalid sort value")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err := app.resolveMovieFilterGenres(v, &input.MovieFilters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	var columns []string
+	for _, column := range exportColumns {
+		if data.HasField(input.Fields, column.name) {
+			columns = append(columns, column.name)
+		}
+	}
+
+	var exporter movieExporter
+	var contentType string
+
+	switch input.Format {
+	case exportFormatCSV:
+		contentType = "text/csv; charset=utf-8"
+		exporter = newCSVExporter(w, columns)
+	default:
+		contentType = "application/x-ndjson"
+		exporter = newNDJSONExporter(w, columns)
+	}
+
+	rc := http.NewResponseController(w)
+	started := false
+
+	err = app.models.Movies.Export(r.Context(), input.MovieFilters, input.Filters, input.Fields, input.Snapshot, func(movies []*data.Movie) error {
+		err := app.loadMovieRelations(r, input.Fields, nil, movies...)
+		if err != nil {
+			return err
+		}
+
+		err = app.localizeMovies(input.Languages, input.Fields, movies...)
+		if err != nil {
+			return err
+		}
+
+		if !started {
+			started = true
+
+			w.Header().Set("Content-Type", contentType)
+			w.Header().Set("Content-Disposition", `attachment; filename="movies.`+input.Format+`"`)
+
+			// Once the first batch is written the status can no longer
+			// change, so the trailer is the only way to tell a client the
+			// export did not finish.
+			w.Header().Set("Trailer", "X-Export-Status")
+		}
+
+		_ = rc.SetWriteDeadline(time.Now().Add(exportBatchDeadline))
+
+		err = exporter.write(movies)
+		if err != nil {
+			return err
+		}
+
+		err = exporter.flush()
+		if err != nil {
+			return err
+		}
+
+		return rc.Flush()
+	})
+	if err != nil {
+		switch {
+		case !started:
+			app.serverErrorResponse(w, r, err)
+		case r.Context().Err() != nil:
+			// The client went away, so there is nobody left to tell.
+		default:
+			w.Header().Set("X-Export-Status", "failed")
+			app.logError(r, err)
+		}
+		return
+	}
+
+	if !started {
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", `attachment; filename="movies.`+input.Format+`"`)
+	}
+
+	err = exporter.flush()
+	if err != nil {
+		app.logError(r, err)
+		return
+	}
+
+	if started {
+		w.Header().Set("X-Export-Status", "complete")
+	}
+}
+
+type csvExporter struct {
+	writer  *csv.Writer
+	columns []string
+	header  bool
+	record  []string
+}
+
+func newCSVExporter(w io.Writer, columns []string) *csvExporter {
+	return &csvExporter{writer: csv.NewWriter(w), columns: columns, record: make([]string, len(columns))}
+}
+
+func (e *csvExporter) write(movies []*data.Movie) error {
+	if !e.header {
+		e.header = true
+
+		err := e.writer.Write(e.columns)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, movie := range movies {
+		i := 0
+		for _, column := range exportColumns {
+			if validator.In(column.name, e.columns...) {
+				e.record[i] = column.csv(movie)
+				i++
+			}
+		}
+
+		err := e.writer.Write(e.record)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (e *csvExporter) flush() error {
+	// An export with no rows still gets its header.
+	if !e.header {
+		err := e.write(nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+type ndjsonExporter struct {
+	encoder *json.Encoder
+	columns []string
+}
+
+func newNDJSONExporter(w io.Writer, columns []string) *ndjsonExporter {
+	return &ndjsonExporter{encoder: json.NewEncoder(w), columns: columns}
+}
+
+func (e *ndjsonExporter) write(movies []*data.Movie) error {
+	for _, movie := range movies {
+		line := make(map[string]interface{}, len(e.columns))
+
+		for _, column := range exportColumns {
+			if validator.In(column.name, e.columns...) {
+				line[column.name] = column.json(movie)
+			}
+		}
+
+		// Encode ends every value with a newline, which is exactly NDJSON.
+		err := e.encoder.Encode(line)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (e *ndjsonExporter) flush() error {
+	return nil
+}
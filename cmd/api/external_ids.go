@@ -0,0 +1,87 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/mrityunjaygr8/greenlight/internal/data"
+	"github.com/mrityunjaygr8/greenlight/internal/validator"
+)
+
+func (app *application) putMovieExternalIDsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var externalIDs map[string]string
+
+	err = app.readJSON(w, r, &externalIDs)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if externalIDs == nil {
+		externalIDs = map[string]string{}
+	}
+
+	v := validator.New()
+
+	if data.ValidateExternalIDs(v, externalIDs); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.ExternalIDs.Set(id, externalIDs)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		case errors.Is(err, data.ErrDuplicateExternalID):
+			v.AddError("external_ids", "contains an ID which already belongs to another movie")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"external_ids": externalIDs}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// lookupMovieHandler finds a movie by its ID at an external source, such as
+// its IMDb ID, and responds as showMovieHandler would.
+func (app *application) lookupMovieHandler(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+
+	source := app.readString(qs, "source", "")
+	externalID := app.readString(qs, "external_id", "")
+
+	v := validator.New()
+
+	v.Check(source != "", "source", "must be provided")
+	v.Check(externalID != "", "external_id", "must be provided")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	id, err := app.models.ExternalIDs.Lookup(source, externalID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.showMovie(w, r, id)
+}
@@ -1,6 +1,9 @@
 package main
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 func (app *application) background(fn func()) {
 	app.wg.Add(1)
@@ -16,3 +19,21 @@ func (app *application) background(fn func()) {
 		fn()
 	}()
 }
+
+// every runs fn once per interval in the background until the server begins
+// shutting down.
+func (app *application) every(interval time.Duration, fn func()) {
+	app.background(func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				fn()
+			case <-app.shutdown:
+				return
+			}
+		}
+	})
+}
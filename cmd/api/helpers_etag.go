@@ -0,0 +1,117 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/mrityunjaygr8/greenlight/internal/data"
+)
+
+// movieETag returns the strong entity tag of a response holding a single
+// movie. The same version of a movie is rendered in many ways, with different
+// fields, includes and languages, for different users, and with ratings and
+// relations which change without the version being bumped, so the tag ends
+// with a hash of the body. It starts with the movie's ID and version, which is
+// all that If-Match on writes is checked against.
+func movieETag(movie *data.Movie, body envelope) (string, error) {
+	sum, err := bodyHash(body)
+	if err != nil {
+		return "", err
+	}
+
+	return movieVersionTag(movie) + sum + `"`, nil
+}
+
+// movieVersionTag is the opening of every entity tag served for the movie's
+// current version.
+func movieVersionTag(movie *data.Movie) string {
+	return fmt.Sprintf(`"%d-%d-`, movie.ID, movie.Version)
+}
+
+// bodyETag returns a strong entity tag for a response made up of many
+// records, computed from its JSON encoding.
+func bodyETag(body envelope) (string, error) {
+	sum, err := bodyHash(body)
+	if err != nil {
+		return "", err
+	}
+
+	return `"` + sum + `"`, nil
+}
+
+func bodyHash(body envelope) (string, error) {
+	js, err := json.Marshal(body)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(js)
+
+	return hex.EncodeToString(sum[:16]), nil
+}
+
+// etagMatches reports whether etag is listed in an If-Match or If-None-Match
+// header. If-None-Match uses the weak comparison, which ignores the W/ prefix,
+// while If-Match requires a strong match.
+func etagMatches(header, etag string, weak bool) bool {
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+
+		if weak {
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+
+		if candidate == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// notModified sets the ETag header and, when it matches the client's
+// If-None-Match, sends 304 Not Modified. It returns true if the response has
+// been written.
+func (app *application) notModified(w http.ResponseWriter, r *http.Request, etag string) bool {
+	w.Header().Set("ETag", etag)
+
+	match := r.Header.Get("If-None-Match")
+	if match == "" || !etagMatches(match, etag, true) {
+		return false
+	}
+
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// moviePreconditionFailed checks the client's If-Match, if any, against the
+// movie and sends 412 Precondition Failed when it does not match. Any tag
+// served for the movie's current version matches, however it was rendered. It
+// returns true if the response has been written.
+func (app *application) moviePreconditionFailed(w http.ResponseWriter, r *http.Request, movie *data.Movie) bool {
+	match := r.Header.Get("If-Match")
+	if match == "" {
+		return false
+	}
+
+	prefix := movieVersionTag(movie)
+
+	for _, candidate := range strings.Split(match, ",") {
+		candidate = strings.TrimSpace(candidate)
+
+		if candidate == "*" || strings.HasPrefix(candidate, prefix) {
+			return false
+		}
+	}
+
+	app.preconditionFailedResponse(w, r)
+	return true
+}
@@ -90,3 +90,35 @@ func (app *application) readJSON(w http.ResponseWriter, r *http.Request, dst int
 	}
 	return nil
 }
+
+// sparseFields cuts the JSON form of v down to the keys in fields, plus those
+// in include so embedded resources survive. With no fields, v is returned as
+// is.
+func sparseFields(v interface{}, fields, include []string) (interface{}, error) {
+	if len(fields) == 0 {
+		return v, nil
+	}
+
+	js, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var all map[string]json.RawMessage
+	err = json.Unmarshal(js, &all)
+	if err != nil {
+		return nil, err
+	}
+
+	sparse := make(map[string]json.RawMessage, len(fields)+len(include))
+
+	for _, keys := range [][]string{fields, include} {
+		for _, key := range keys {
+			if value, ok := all[key]; ok {
+				sparse[key] = value
+			}
+		}
+	}
+
+	return sparse, nil
+}
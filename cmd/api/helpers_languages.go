@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mrityunjaygr8/greenlight/internal/data"
+	"github.com/mrityunjaygr8/greenlight/internal/validator"
+)
+
+// maxPreferredLanguages bounds how many languages a client can ask for, which
+// keeps fallback chains short whatever the Accept-Language header holds.
+const maxPreferredLanguages = 10
+
+// readLanguages returns the languages the client wants movie titles in, most
+// preferred first. The lang parameter, a comma-separated list of tags, takes
+// precedence over the Accept-Language header.
+func (app *application) readLanguages(r *http.Request, v *validator.Validator) []string {
+	languages := app.readLanguageParam(r.URL.Query(), v)
+	if languages != nil {
+		return languages
+	}
+
+	return parseAcceptLanguage(r.Header.Get("Accept-Language"))
+}
+
+// readLanguageParam reads the lang parameter alone, for endpoints whose output
+// should not change with the headers a browser happens to send.
+func (app *application) readLanguageParam(qs url.Values, v *validator.Validator) []string {
+	languages := app.readCSV(qs, "lang", nil)
+	if languages == nil {
+		return nil
+	}
+
+	for i, tag := range languages {
+		if !validator.Matches(tag, data.LanguageTagRX) {
+			v.AddError("lang", "must be a comma-separated list of BCP 47 language tags")
+			return nil
+		}
+
+		languages[i] = data.CanonicalLanguageTag(tag)
+	}
+
+	v.Check(len(languages) <= maxPreferredLanguages, "lang", fmt.Sprintf("must not contain more than %d languages", maxPreferredLanguages))
+
+	return languages
+}
+
+// parseAcceptLanguage returns the tags in an Accept-Language header ordered
+// by quality. Malformed entries, the * wildcard and tags with a quality of 0
+// are dropped rather than failing the request, since browsers send the header
+// unasked.
+func parseAcceptLanguage(header string) []string {
+	type weighted struct {
+		tag     string
+		quality float64
+	}
+
+	var entries []weighted
+
+	for _, entry := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(entry), ";")
+		tag = strings.TrimSpace(tag)
+
+		if !validator.Matches(tag, data.LanguageTagRX) {
+			continue
+		}
+
+		quality := 1.0
+
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(q, 64)
+			if err != nil {
+				continue
+			}
+			quality = parsed
+		}
+
+		if quality <= 0 {
+			continue
+		}
+
+		entries = append(entries, weighted{data.CanonicalLanguageTag(tag), quality})
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].quality > entries[j].quality
+	})
+
+	if len(entries) > maxPreferredLanguages {
+		entries = entries[:maxPreferredLanguages]
+	}
+
+	languages := make([]string, len(entries))
+	for i, entry := range entries {
+		languages[i] = entry.tag
+	}
+
+	return languages
+}
+
+// languageFallbacks expands preferred languages into the order in which
+// translations are tried. Each tag is followed by ever shorter forms of
+// itself before the next preference is tried, as in RFC 4647 lookup, so
+// "pt-BR, en" becomes pt-BR, pt, en.
+func languageFallbacks(languages []string) []string {
+	var chain []string
+
+	for _, tag := range languages {
+		for {
+			if !validator.In(tag, chain...) {
+				chain = append(chain, tag)
+			}
+
+			i := strings.LastIndex(tag, "-")
+			if i == -1 {
+				break
+			}
+			tag = tag[:i]
+		}
+	}
+
+	return chain
+}
@@ -0,0 +1,397 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mrityunjaygr8/greenlight/internal/data"
+	"github.com/mrityunjaygr8/greenlight/internal/imaging"
+	"github.com/mrityunjaygr8/greenlight/internal/storage"
+	"github.com/mrityunjaygr8/greenlight/internal/validator"
+)
+
+const (
+	maxImageBytes     = 10 << 20
+	maxImageDimension = 4096
+	blobTimeout       = 30 * time.Second
+)
+
+// orphanBlobAge is how old a blob must be before the cleaner may remove it,
+// which keeps it clear of uploads whose image row is not yet written.
+const orphanBlobAge = time.Hour
+
+// movieImageSpecs holds the size limits and thumbnail bounds of each kind of
+// movie image. Posters must be portrait and backdrops landscape.
+var movieImageSpecs = map[string]struct {
+	minWidth, minHeight     int
+	thumbWidth, thumbHeight int
+	portrait                bool
+}{
+	data.MovieImagePoster:   {minWidth: 200, minHeight: 300, thumbWidth: 185, thumbHeight: 278, portrait: true},
+	data.MovieImageBackdrop: {minWidth: 640, minHeight: 360, thumbWidth: 780, thumbHeight: 439},
+}
+
+// setMovieImages fills in whichever of the poster and backdrop are part of
+// fields.
+func (app *application) setMovieImages(fields []string, movies ...*data.Movie) error {
+	ids := make([]int64, len(movies))
+	for i, movie := range movies {
+		ids[i] = movie.ID
+	}
+
+	images, err := app.models.Images.GetForMovies(ids)
+	if err != nil {
+		return err
+	}
+
+	for _, movie := range movies {
+		for _, image := range images[movie.ID] {
+			app.setImageURLs(image)
+
+			switch {
+			case image.Kind == data.MovieImagePoster && data.HasField(fields, "poster"):
+				movie.Poster = image
+			case image.Kind == data.MovieImageBackdrop && data.HasField(fields, "backdrop"):
+				movie.Backdrop = image
+			}
+		}
+	}
+
+	return nil
+}
+
+func (app *application) setImageURLs(image *data.MovieImage) {
+	image.URL = app.blobs.URL(image.Key)
+	image.ThumbnailURL = app.blobs.URL(image.ThumbnailKey)
+}
+
+func (app *application) putMovieImageHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	kind := app.readStringParam(r, "kind")
+
+	spec, ok := movieImageSpecs[kind]
+	if !ok {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	_, err = app.models.Movies.GetFields(id, []string{"id"})
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/form-data" {
+		app.unsupportedMediaTypeResponse(w, r)
+		return
+	}
+
+	upload, err := app.readImageUpload(w, r)
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+
+		switch {
+		case errors.As(err, &maxBytesError):
+			app.badRequestResponse(w, r, fmt.Errorf("body must not be larger than %d bytes", maxBytesError.Limit))
+		default:
+			app.badRequestResponse(w, r, err)
+		}
+		return
+	}
+
+	v := validator.New()
+
+	v.Check(len(upload) > 0, "image", "must be provided")
+	v.Check(len(upload) <= maxImageBytes, "image", fmt.Sprintf("must not be larger than %d bytes", maxImageBytes))
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	contentType, err := imaging.DetectContentType(upload)
+	if err != nil {
+		v.AddError("image", "must be a JPEG or PNG image")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// Only the header is read at first, so images with absurd dimensions are
+	// rejected before any memory is spent decoding them.
+	width, height, err := imaging.DecodeConfig(upload)
+	if err != nil {
+		v.AddError("image", "must be a valid image")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	v.Check(width >= spec.minWidth && height >= spec.minHeight, "image", fmt.Sprintf("must be at least %dx%d pixels", spec.minWidth, spec.minHeight))
+	v.Check(width <= maxImageDimension && height <= maxImageDimension, "image", fmt.Sprintf("must not be more than %d pixels wide or high", maxImageDimension))
+
+	if spec.portrait {
+		v.Check(height > width, "image", "must be portrait")
+	} else {
+		v.Check(width > height, "image", "must be landscape")
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	img, err := imaging.Decode(upload)
+	if err != nil {
+		v.AddError("image", "must be a valid image")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	image := &data.MovieImage{
+		MovieID:     id,
+		Kind:        kind,
+		ContentType: contentType,
+		Width:       width,
+		Height:      height,
+	}
+
+	image.Key, image.ThumbnailKey, err = app.storeMovieImage(r.Context(), img, id, kind, spec.thumbWidth, spec.thumbHeight)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	previous, err := app.models.Images.Put(image)
+	if err != nil {
+		app.deleteImageBlobs(image)
+
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if previous != nil {
+		app.deleteImageBlobs(previous)
+	}
+
+	app.setImageURLs(image)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"image": image}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// readImageUpload returns the contents of the "image" field of a multipart
+// form, or nil if there is none. It reads one byte past maxImageBytes, so
+// that oversized uploads can be reported as such.
+func (app *application) readImageUpload(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	// The limit leaves room for the multipart boundaries and headers.
+	r.Body = http.MaxBytesReader(w, r.Body, maxImageBytes+1<<20)
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if part.FormName() != "image" {
+			continue
+		}
+
+		return io.ReadAll(io.LimitReader(part, maxImageBytes+1))
+	}
+}
+
+// storeMovieImage writes the image and a thumbnail of it to the blob store.
+// Both are re-encoded from the decoded image, which drops any EXIF data the
+// upload carried. Keys are random, so a replaced image never shares a URL
+// with its successor and blobs can be cached indefinitely.
+func (app *application) storeMovieImage(ctx context.Context, img *imaging.Image, movieID int64, kind string, thumbWidth, thumbHeight int) (string, string, error) {
+	token := make([]byte, 8)
+
+	_, err := rand.Read(token)
+	if err != nil {
+		return "", "", err
+	}
+
+	prefix := fmt.Sprintf("movies/%d/%s-%s", movieID, kind, hex.EncodeToString(token))
+	key := prefix + img.Extension()
+	thumbnailKey := prefix + "-thumb" + img.Extension()
+
+	ctx, cancel := context.WithTimeout(ctx, blobTimeout)
+	defer cancel()
+
+	for _, blob := range []struct {
+		key string
+		img *imaging.Image
+	}{
+		{key, img},
+		{thumbnailKey, img.Fit(thumbWidth, thumbHeight)},
+	} {
+		var buf bytes.Buffer
+
+		err = blob.img.Encode(&buf)
+		if err != nil {
+			return "", "", err
+		}
+
+		err = app.blobs.Put(ctx, blob.key, &buf, img.ContentType)
+		if err != nil {
+			return "", "", err
+		}
+	}
+
+	return key, thumbnailKey, nil
+}
+
+// deleteImageBlobs removes an image's blobs once nothing refers to them. It is
+// best effort: anything left behind is picked up by the orphan cleaner.
+func (app *application) deleteImageBlobs(image *data.MovieImage) {
+	ctx, cancel := context.WithTimeout(context.Background(), blobTimeout)
+	defer cancel()
+
+	for _, key := range []string{image.Key, image.ThumbnailKey} {
+		err := app.blobs.Delete(ctx, key)
+		if err != nil {
+			app.logger.PrintError(err, map[string]string{"key": key})
+		}
+	}
+}
+
+func (app *application) deleteMovieImageHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	kind := app.readStringParam(r, "kind")
+	if !validator.In(kind, data.MovieImageKinds...) {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	image, err := app.models.Images.Delete(id, kind)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.deleteImageBlobs(image)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": fmt.Sprintf("%s successfully deleted", kind)}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// startBlobCleaner removes blobs which no image refers to, such as those of
+// purged movies or of uploads which failed part way, once per cleanup
+// interval.
+func (app *application) startBlobCleaner() {
+	app.every(app.config.blobs.cleanupInterval, func() {
+		removed, err := app.removeOrphanBlobs()
+		if err != nil {
+			app.logger.PrintError(err, nil)
+		}
+
+		if removed > 0 {
+			app.logger.PrintInfo("removed orphaned blobs", map[string]string{
+				"count": strconv.Itoa(removed),
+			})
+		}
+	})
+}
+
+func (app *application) removeOrphanBlobs() (int, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cutoff := time.Now().Add(-orphanBlobAge)
+	removed := 0
+
+	// Blobs are checked against the database in batches as the store is
+	// walked, rather than loading every key at once.
+	var keys []string
+
+	sweep := func() error {
+		referenced, err := app.models.Images.Referenced(keys)
+		if err != nil {
+			return err
+		}
+
+		for _, key := range keys {
+			if referenced[key] {
+				continue
+			}
+
+			err := app.blobs.Delete(ctx, key)
+			if err != nil {
+				return err
+			}
+			removed++
+		}
+
+		keys = keys[:0]
+		return nil
+	}
+
+	err := app.blobs.List(ctx, func(blob storage.Blob) error {
+		if blob.ModTime.After(cutoff) {
+			return nil
+		}
+
+		keys = append(keys, blob.Key)
+		if len(keys) < 500 {
+			return nil
+		}
+
+		return sweep()
+	})
+	if err != nil {
+		return removed, err
+	}
+
+	if len(keys) > 0 {
+		err = sweep()
+	}
+
+	return removed, err
+}
@@ -0,0 +1,380 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mrityunjaygr8/greenlight/internal/data"
+	"github.com/mrityunjaygr8/greenlight/internal/validator"
+)
+
+const (
+	maxImportBytes = 100 << 20
+	maxImportRows  = 100_000
+	importDeadline = 5 * time.Minute
+)
+
+// importRecord is one movie in an import file: the fields of a create
+// request plus an optional ID from the catalog the file was exported from.
+type importRecord struct {
+	MovieRequest
+	ExternalID string `json:"external_id"`
+}
+
+func (app *application) importMoviesHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		DryRun bool
+		Upsert bool
+		Source string
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	input.DryRun = app.readBool(qs, "dry_run", false, v)
+	input.Upsert = app.readBool(qs, "upsert", false, v)
+	input.Source = app.readString(qs, "source", "import")
+
+	if data.ValidateExternalSource(v, input.Source); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		app.unsupportedMediaTypeResponse(w, r)
+		return
+	}
+
+	var read func(io.Reader) ([]importRecord, []int, []data.ImportRowError, error)
+
+	switch mediaType {
+	case "text/csv":
+		read = readCSVImport
+	case "application/x-ndjson", "application/jsonl":
+		read = readNDJSONImport
+	default:
+		app.unsupportedMediaTypeResponse(w, r)
+		return
+	}
+
+	// Large catalogs take longer to upload and write than the server's usual
+	// timeouts allow. Extending them is best effort, as not every
+	// ResponseWriter supports it.
+	rc := http.NewResponseController(w)
+	_ = rc.SetReadDeadline(time.Now().Add(importDeadline))
+	_ = rc.SetWriteDeadline(time.Now().Add(importDeadline))
+
+	body := http.MaxBytesReader(w, r.Body, maxImportBytes)
+
+	records, lines, parseErrors, err := read(body)
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+
+		switch {
+		case errors.As(err, &maxBytesError):
+			app.badRequestResponse(w, r, fmt.Errorf("import must not be larger than %d bytes", maxBytesError.Limit))
+		default:
+			app.badRequestResponse(w, r, err)
+		}
+		return
+	}
+
+	rows, rowErrors, err := app.validateImport(records, lines)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	rowErrors = append(rowErrors, parseErrors...)
+
+	sort.Slice(rowErrors, func(i, j int) bool {
+		return rowErrors[i].Line < rowErrors[j].Line
+	})
+
+	params, err := json.Marshal(data.ImportParams{Source: input.Source, Upsert: input.Upsert, DryRun: input.DryRun})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// Rows which failed validation are counted as processed straight away;
+	// only the valid ones are left for the job's workers.
+	job := &data.Job{
+		UserID:    app.contextGetUser(r).ID,
+		Params:    params,
+		Total:     len(records) + len(parseErrors),
+		Processed: len(rowErrors),
+		Failed:    len(rowErrors),
+		Errors:    rowErrors,
+	}
+
+	err = app.models.Jobs.InsertImport(job, rows)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.wakeJobWorkers()
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/jobs/%d", job.ID))
+
+	err = app.writeJSON(w, http.StatusAccepted, envelope{"job": job}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// validateImport checks every record as a create request would, resolving all
+// of the genres named in the file with a single lookup. Records which pass
+// become rows ready to be queued as an import job; the rest are reported.
+func (app *application) validateImport(records []importRecord, lines []int) ([]*data.ImportRow, []data.ImportRowError, error) {
+	canonical, err := app.resolveImportGenres(records)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rows := []*data.ImportRow{}
+	rowErrors := []data.ImportRowError{}
+	seen := make(map[string]int)
+
+	for i, record := range records {
+		movie := &data.Movie{
+			Title:   record.Title,
+			Year:    record.Year,
+			Runtime: record.Runtime,
+			Genres:  record.Genres,
+		}
+
+		v := validator.New()
+
+		data.ValidateMovie(v, movie)
+		data.ValidateExternalID(v, record.ExternalID)
+
+		if line, ok := seen[record.ExternalID]; ok && record.ExternalID != "" {
+			v.AddError("external_id", fmt.Sprintf("duplicates line %d", line))
+		}
+		seen[record.ExternalID] = lines[i]
+
+		if v.Valid() {
+			movie.Genres = make([]string, len(record.Genres))
+
+			var unknown []string
+			for j, name := range record.Genres {
+				slug, ok := canonical[name]
+				if !ok {
+					unknown = append(unknown, name)
+				}
+				movie.Genres[j] = slug
+			}
+
+			if len(unknown) > 0 {
+				v.AddError("genres", fmt.Sprintf("contains unknown genres: %s", strings.Join(unknown, ", ")))
+			} else {
+				v.Check(validator.Unique(movie.Genres), "genres", "must not contain duplicate values")
+			}
+		}
+
+		if !v.Valid() {
+			rowErrors = append(rowErrors, data.ImportRowError{Line: lines[i], ExternalID: record.ExternalID, Errors: v.Errors})
+			continue
+		}
+
+		rows = append(rows, &data.ImportRow{Line: lines[i], ExternalID: record.ExternalID, Movie: movie})
+	}
+
+	return rows, rowErrors, nil
+}
+
+// resolveImportGenres maps every distinct genre name in the records to its
+// canonical slug. Unknown names are left out of the map.
+func (app *application) resolveImportGenres(records []importRecord) (map[string]string, error) {
+	names := []string{}
+	seen := make(map[string]bool)
+
+	for _, record := range records {
+		for _, name := range record.Genres {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+
+	canonical := make(map[string]string, len(names))
+
+	if len(names) == 0 {
+		return canonical, nil
+	}
+
+	slugs, unknown, err := app.models.Genres.Resolve(names)
+	if err != nil {
+		return nil, err
+	}
+
+	// Resolve returns the slugs of the known names in order, skipping the
+	// unknown ones, so the two can be zipped back together.
+	known := slugs
+	for _, name := range names {
+		if validator.In(name, unknown...) {
+			continue
+		}
+
+		canonical[name] = known[0]
+		known = known[1:]
+	}
+
+	return canonical, nil
+}
+
+// readNDJSONImport reads one JSON movie per line, skipping blank lines. Lines
+// which are not valid movie objects are reported rather than failing the
+// whole import.
+func readNDJSONImport(body io.Reader) ([]importRecord, []int, []data.ImportRowError, error) {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 1_048_576)
+
+	records := []importRecord{}
+	lines := []int{}
+	rowErrors := []data.ImportRowError{}
+	line := 0
+
+	for scanner.Scan() {
+		line++
+
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+
+		if len(records)+len(rowErrors) == maxImportRows {
+			return nil, nil, nil, fmt.Errorf("import must not contain more than %d rows", maxImportRows)
+		}
+
+		var record importRecord
+
+		dec := json.NewDecoder(bytes.NewReader(text))
+		dec.DisallowUnknownFields()
+
+		err := dec.Decode(&record)
+		if err != nil {
+			rowErrors = append(rowErrors, data.ImportRowError{Line: line, Errors: map[string]string{"json": err.Error()}})
+			continue
+		}
+
+		records = append(records, record)
+		lines = append(lines, line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, nil, nil, err
+	}
+
+	return records, lines, rowErrors, nil
+}
+
+// readCSVImport reads movies from CSV with a header row naming the columns:
+// title, year, runtime (in minutes), genres (separated by |) and, optionally,
+// external_id. Rows with the wrong number of fields are reported rather than
+// failing the whole import.
+func readCSVImport(body io.Reader) ([]importRecord, []int, []data.ImportRowError, error) {
+	reader := csv.NewReader(body)
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil, nil, errors.New("import must not be empty")
+		}
+		return nil, nil, nil, err
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.TrimSpace(name)
+
+		if !validator.In(name, "title", "year", "runtime", "genres", "external_id") {
+			return nil, nil, nil, fmt.Errorf("header contains unknown column %q", name)
+		}
+
+		columns[name] = i
+	}
+
+	for _, name := range []string{"title", "year", "runtime", "genres"} {
+		if _, ok := columns[name]; !ok {
+			return nil, nil, nil, fmt.Errorf("header must contain a %q column", name)
+		}
+	}
+
+	field := func(fields []string, name string) string {
+		i, ok := columns[name]
+		if !ok {
+			return ""
+		}
+		return strings.TrimSpace(fields[i])
+	}
+
+	records := []importRecord{}
+	lines := []int{}
+	rowErrors := []data.ImportRowError{}
+
+	for {
+		fields, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if len(records)+len(rowErrors) == maxImportRows {
+			return nil, nil, nil, fmt.Errorf("import must not contain more than %d rows", maxImportRows)
+		}
+
+		var parseError *csv.ParseError
+		if errors.As(err, &parseError) && errors.Is(err, csv.ErrFieldCount) {
+			rowErrors = append(rowErrors, data.ImportRowError{Line: parseError.StartLine, Errors: map[string]string{"csv": parseError.Err.Error()}})
+			continue
+		}
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		line, _ := reader.FieldPos(0)
+
+		// Numbers which fail to parse are left as zero for ValidateMovie to
+		// report against the row.
+		year, _ := strconv.ParseInt(field(fields, "year"), 10, 32)
+		runtime, _ := strconv.ParseInt(field(fields, "runtime"), 10, 32)
+
+		var genres []string
+		for _, genre := range strings.Split(field(fields, "genres"), "|") {
+			if genre = strings.TrimSpace(genre); genre != "" {
+				genres = append(genres, genre)
+			}
+		}
+
+		records = append(records, importRecord{
+			MovieRequest: MovieRequest{
+				Title:   field(fields, "title"),
+				Year:    int32(year),
+				Runtime: data.Runtime(runtime),
+				Genres:  genres,
+			},
+			ExternalID: field(fields, "external_id"),
+		})
+		lines = append(lines, line)
+	}
+
+	return records, lines, rowErrors, nil
+}
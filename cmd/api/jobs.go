@@ -0,0 +1,205 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mrityunjaygr8/greenlight/internal/data"
+)
+
+// jobPollInterval is how often idle workers look for queued jobs. Jobs queued
+// by this process wake a worker straight away, so polling only matters for
+// jobs resumed after a restart.
+const jobPollInterval = 5 * time.Second
+
+// startJobWorkers requeues any jobs a previous process left running and then
+// starts the worker pool. Workers are tracked by app.wg and stop between
+// batches once the server begins shutting down.
+func (app *application) startJobWorkers() {
+	requeued, err := app.models.Jobs.RequeueInterrupted()
+	if err != nil {
+		app.logger.PrintError(err, nil)
+	}
+
+	if requeued > 0 {
+		app.logger.PrintInfo("requeued interrupted jobs", map[string]string{
+			"count": strconv.FormatInt(requeued, 10),
+		})
+	}
+
+	for i := 0; i < app.config.jobs.workers; i++ {
+		app.background(app.jobWorker)
+	}
+}
+
+// wakeJobWorkers tells an idle worker that a job has been queued.
+func (app *application) wakeJobWorkers() {
+	select {
+	case app.jobQueued <- struct{}{}:
+	default:
+	}
+}
+
+func (app *application) jobWorker() {
+	ticker := time.NewTicker(jobPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-app.shutdown:
+			return
+		default:
+		}
+
+		job, err := app.models.Jobs.Claim()
+		switch {
+		case err == nil:
+			app.runImportJob(job)
+			continue
+		case !errors.Is(err, data.ErrNoJobsQueued):
+			app.logger.PrintError(err, nil)
+		}
+
+		select {
+		case <-app.shutdown:
+			return
+		case <-app.jobQueued:
+		case <-ticker.C:
+		}
+	}
+}
+
+// runImportJob writes an import job one batch at a time until it is done, is
+// cancelled or the server shuts down, in which case the job goes back to the
+// queue to be resumed by the next process.
+func (app *application) runImportJob(job *data.Job) {
+	for {
+		select {
+		case <-app.shutdown:
+			err := app.models.Jobs.Requeue(job.ID)
+			if err != nil && !errors.Is(err, data.ErrInvalidJobStatus) {
+				app.logger.PrintError(err, nil)
+			}
+			return
+		default:
+		}
+
+		rows, err := app.models.Jobs.NextImportRows(job, data.ImportBatchSize)
+		if err != nil {
+			app.failJob(job, err)
+			return
+		}
+
+		if len(rows) == 0 {
+			err = app.models.Jobs.Finish(job, data.JobStatusSucceeded, "")
+			if err != nil && !errors.Is(err, data.ErrJobNotRunning) {
+				app.logger.PrintError(err, nil)
+			}
+			return
+		}
+
+		err = app.models.Jobs.ImportBatch(job, rows)
+		switch {
+		case errors.Is(err, data.ErrJobNotRunning):
+			return
+		case err != nil:
+			app.failJob(job, err)
+			return
+		}
+	}
+}
+
+// failJob records that a job stopped on an unexpected error. The details are
+// only logged; the job reports a generic message and can be resumed.
+func (app *application) failJob(job *data.Job, err error) {
+	app.logger.PrintError(err, map[string]string{
+		"job_id": strconv.FormatInt(job.ID, 10),
+	})
+
+	err = app.models.Jobs.Finish(job, data.JobStatusFailed, "the job stopped because of an internal error, it can be resumed")
+	if err != nil && !errors.Is(err, data.ErrJobNotRunning) {
+		app.logger.PrintError(err, nil)
+	}
+}
+
+// getOwnedJob loads the job named in the URL. Other users' jobs are reported
+// as not found. It writes the error response itself and returns nil when the
+// handler should stop.
+func (app *application) getOwnedJob(w http.ResponseWriter, r *http.Request) *data.Job {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil
+	}
+
+	job, err := app.models.Jobs.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil
+	}
+
+	if job.UserID != app.contextGetUser(r).ID {
+		app.notFoundResponse(w, r)
+		return nil
+	}
+
+	return job
+}
+
+func (app *application) showJobHandler(w http.ResponseWriter, r *http.Request) {
+	job := app.getOwnedJob(w, r)
+	if job == nil {
+		return
+	}
+
+	err := app.writeJSON(w, http.StatusOK, envelope{"job": job}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) cancelJobHandler(w http.ResponseWriter, r *http.Request) {
+	app.changeJobStatus(w, r, app.models.Jobs.Cancel)
+}
+
+func (app *application) resumeJobHandler(w http.ResponseWriter, r *http.Request) {
+	app.changeJobStatus(w, r, app.models.Jobs.Resume)
+}
+
+func (app *application) changeJobStatus(w http.ResponseWriter, r *http.Request, change func(id int64) error) {
+	job := app.getOwnedJob(w, r)
+	if job == nil {
+		return
+	}
+
+	err := change(job.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrInvalidJobStatus):
+			app.invalidJobStatusResponse(w, r, job.Status)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.wakeJobWorkers()
+
+	job, err = app.models.Jobs.Get(job.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"job": job}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,396 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/mrityunjaygr8/greenlight/internal/data"
+	"github.com/mrityunjaygr8/greenlight/internal/validator"
+)
+
+func (app *application) readListFilters(w http.ResponseWriter, r *http.Request) (data.Filters, bool) {
+	var filters data.Filters
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	filters.Page = app.readInt(qs, "page", 1, v)
+	filters.PageSize = app.readInt(qs, "page_size", 20, v)
+
+	filters.Sort = app.readString(qs, "sort", "-created_at")
+	filters.SortSafelist = []string{"id", "created_at", "title", "-id", "-created_at", "-title"}
+
+	if data.ValidateFilters(v, filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return filters, false
+	}
+
+	return filters, true
+}
+
+func (app *application) listPublicListsHandler(w http.ResponseWriter, r *http.Request) {
+	filters, ok := app.readListFilters(w, r)
+	if !ok {
+		return
+	}
+
+	lists, metadata, err := app.models.Lists.GetAll(0, data.ListVisibilityPublic, filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"lists": lists, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listMyListsHandler(w http.ResponseWriter, r *http.Request) {
+	filters, ok := app.readListFilters(w, r)
+	if !ok {
+		return
+	}
+
+	lists, metadata, err := app.models.Lists.GetAll(app.contextGetUser(r).ID, "", filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"lists": lists, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) createListHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Title       string `json:"title"`
+		Description string `json:"description"`
+		Visibility  string `json:"visibility"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	list := &data.List{
+		UserID:      app.contextGetUser(r).ID,
+		Title:       input.Title,
+		Description: input.Description,
+		Visibility:  input.Visibility,
+	}
+
+	if list.Visibility == "" {
+		list.Visibility = data.ListVisibilityPrivate
+	}
+
+	v := validator.New()
+
+	if data.ValidateList(v, list); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Lists.Insert(list)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/lists/%d", list.ID))
+	err = app.writeJSON(w, http.StatusCreated, envelope{"list": list}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) writeListWithEntries(w http.ResponseWriter, r *http.Request, list *data.List) {
+	var err error
+
+	list.Entries, err = app.models.Lists.GetEntries(list.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"list": list}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) showListHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	list, err := app.models.Lists.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if !list.CanBeViewedBy(app.contextGetUser(r)) {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	app.writeListWithEntries(w, r, list)
+}
+
+func (app *application) showSharedListHandler(w http.ResponseWriter, r *http.Request) {
+	slug := app.readStringParam(r, "slug")
+
+	list, err := app.models.Lists.GetBySlug(slug)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if list.Visibility == data.ListVisibilityPrivate && list.UserID != app.contextGetUser(r).ID {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	app.writeListWithEntries(w, r, list)
+}
+
+// getOwnedList loads the list named in the URL and checks that it belongs to
+// the current user. Lists the user cannot see are reported as not found rather
+// than forbidden so that private lists do not leak. It writes the error
+// response itself and returns nil when the handler should stop.
+func (app *application) getOwnedList(w http.ResponseWriter, r *http.Request) *data.List {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil
+	}
+
+	list, err := app.models.Lists.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil
+	}
+
+	user := app.contextGetUser(r)
+
+	if list.UserID != user.ID {
+		if list.CanBeViewedBy(user) {
+			app.notPermittedResponse(w, r)
+		} else {
+			app.notFoundResponse(w, r)
+		}
+		return nil
+	}
+
+	return list
+}
+
+func (app *application) updateListHandler(w http.ResponseWriter, r *http.Request) {
+	list := app.getOwnedList(w, r)
+	if list == nil {
+		return
+	}
+
+	var input struct {
+		Title       *string `json:"title"`
+		Description *string `json:"description"`
+		Visibility  *string `json:"visibility"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Title != nil {
+		list.Title = *input.Title
+	}
+
+	if input.Description != nil {
+		list.Description = *input.Description
+	}
+
+	if input.Visibility != nil {
+		list.Visibility = *input.Visibility
+	}
+
+	v := validator.New()
+
+	if data.ValidateList(v, list); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Lists.Update(list)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"list": list}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteListHandler(w http.ResponseWriter, r *http.Request) {
+	list := app.getOwnedList(w, r)
+	if list == nil {
+		return
+	}
+
+	err := app.models.Lists.Delete(list.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "list deleted successfully"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) addListEntriesHandler(w http.ResponseWriter, r *http.Request) {
+	list := app.getOwnedList(w, r)
+	if list == nil {
+		return
+	}
+
+	var input struct {
+		Entries []struct {
+			MovieID int64  `json:"movie_id"`
+			Note    string `json:"note"`
+		} `json:"entries"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	entries := make([]*data.ListEntry, len(input.Entries))
+	for i, entry := range input.Entries {
+		entries[i] = &data.ListEntry{MovieID: entry.MovieID, Note: entry.Note}
+	}
+
+	v := validator.New()
+
+	if data.ValidateListEntries(v, entries); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Lists.AddEntries(list.ID, entries)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("entries", "must only contain existing movies")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.writeListWithEntries(w, r, list)
+}
+
+func (app *application) deleteListEntryHandler(w http.ResponseWriter, r *http.Request) {
+	list := app.getOwnedList(w, r)
+	if list == nil {
+		return
+	}
+
+	movieID, err := app.readNamedIDParam(r, "movie_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.Lists.RemoveEntry(list.ID, movieID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "movie removed from list"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) reorderListHandler(w http.ResponseWriter, r *http.Request) {
+	list := app.getOwnedList(w, r)
+	if list == nil {
+		return
+	}
+
+	var input struct {
+		MovieIDs []int64 `json:"movie_ids"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	err = app.models.Lists.Reorder(list.ID, input.MovieIDs)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrInvalidListOrder):
+			v.AddError("movie_ids", "must contain every movie on the list exactly once")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.writeListWithEntries(w, r, list)
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/mrityunjaygr8/greenlight/internal/data"
+	"github.com/mrityunjaygr8/greenlight/internal/validator"
+)
+
+// localizeMovies swaps each movie's title for its translation into the first
+// language of the fallback chain it has been translated into, keeping the
+// original in original_title. Movies translated into none of the languages
+// keep their original title and have no original_title, language or
+// synopsis.
+func (app *application) localizeMovies(languages, fields []string, movies ...*data.Movie) error {
+	if len(languages) == 0 || len(movies) == 0 {
+		return nil
+	}
+
+	localized := false
+	for _, field := range []string{"title", "original_title", "language", "synopsis"} {
+		localized = localized || data.HasField(fields, field)
+	}
+
+	if !localized {
+		return nil
+	}
+
+	ids := make([]int64, len(movies))
+	for i, movie := range movies {
+		ids[i] = movie.ID
+	}
+
+	translations, err := app.models.Localizations.GetTranslations(ids)
+	if err != nil {
+		return err
+	}
+
+	chain := languageFallbacks(languages)
+
+	for _, movie := range movies {
+		translation := pickTranslation(translations[movie.ID], chain)
+		if translation == nil {
+			continue
+		}
+
+		movie.OriginalTitle = movie.Title
+		movie.Title = translation.Title
+		movie.Synopsis = translation.Synopsis
+		movie.Language = translation.Language
+	}
+
+	return nil
+}
+
+// pickTranslation returns the translation into the earliest language in
+// chain, or nil if there is none.
+func pickTranslation(translations []*data.MovieTranslation, chain []string) *data.MovieTranslation {
+	for _, language := range chain {
+		for _, translation := range translations {
+			if translation.Language == language {
+				return translation
+			}
+		}
+	}
+
+	return nil
+}
+
+// setMovieLocalizations embeds whichever of the translations and releases are
+// part of include.
+func (app *application) setMovieLocalizations(include []string, movies ...*data.Movie) error {
+	ids := make([]int64, len(movies))
+	for i, movie := range movies {
+		ids[i] = movie.ID
+	}
+
+	if validator.In(data.MovieIncludeTranslations, include...) {
+		translations, err := app.models.Localizations.GetTranslations(ids)
+		if err != nil {
+			return err
+		}
+
+		for _, movie := range movies {
+			movie.Translations = translations[movie.ID]
+		}
+	}
+
+	if validator.In(data.MovieIncludeReleases, include...) {
+		releases, err := app.models.Localizations.GetReleases(ids)
+		if err != nil {
+			return err
+		}
+
+		for _, movie := range movies {
+			movie.Releases = releases[movie.ID]
+		}
+	}
+
+	return nil
+}
+
+func (app *application) putMovieTranslationHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	language := app.readStringParam(r, "lang")
+	if !validator.Matches(language, data.LanguageTagRX) {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Title    string `json:"title"`
+		Synopsis string `json:"synopsis"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	translation := &data.MovieTranslation{
+		Language: data.CanonicalLanguageTag(language),
+		Title:    input.Title,
+		Synopsis: input.Synopsis,
+	}
+
+	v := validator.New()
+
+	if data.ValidateMovieTranslation(v, translation); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Localizations.PutTranslation(id, translation)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"translation": translation}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteMovieTranslationHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	language := app.readStringParam(r, "lang")
+	if !validator.Matches(language, data.LanguageTagRX) {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.Localizations.DeleteTranslation(id, data.CanonicalLanguageTag(language))
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "translation successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) putMovieReleasesHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var releases []*data.MovieRelease
+
+	err = app.readJSON(w, r, &releases)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	for _, release := range releases {
+		if release == nil {
+			v.AddError("releases", "must not contain null")
+			break
+		}
+
+		release.Country = strings.ToUpper(release.Country)
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if data.ValidateMovieReleases(v, releases); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Localizations.SetReleases(id, releases)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if releases == nil {
+		releases = []*data.MovieRelease{}
+	}
+
+	sort.Slice(releases, func(i, j int) bool {
+		return releases[i].Country < releases[j].Country
+	})
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"releases": releases}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
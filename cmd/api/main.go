@@ -2,12 +2,14 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
 	"database/sql"
 	"expvar"
 	"flag"
 	"os"
 	"runtime"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -15,6 +17,8 @@ import (
 	"github.com/mrityunjaygr8/greenlight/internal/data"
 	"github.com/mrityunjaygr8/greenlight/internal/jsonlog"
 	"github.com/mrityunjaygr8/greenlight/internal/mailer"
+	"github.com/mrityunjaygr8/greenlight/internal/recommend"
+	"github.com/mrityunjaygr8/greenlight/internal/storage"
 )
 
 const version = "1.0.0"
@@ -37,14 +41,41 @@ type config struct {
 		sender   string
 		enable   bool
 	}
+	cursor struct {
+		secret string
+	}
+	trash struct {
+		retention     time.Duration
+		purgeInterval time.Duration
+	}
+	jobs struct {
+		workers int
+	}
+	blobs struct {
+		dir             string
+		baseURL         string
+		cleanupInterval time.Duration
+	}
+	recommendations struct {
+		refreshInterval time.Duration
+	}
+	stats struct {
+		cacheTTL time.Duration
+	}
 }
 
 type application struct {
-	config config
-	logger *jsonlog.Logger
-	models data.Models
-	mailer mailer.Mailer
-	wg     sync.WaitGroup
+	config    config
+	logger    *jsonlog.Logger
+	models    data.Models
+	mailer    mailer.Mailer
+	wg        sync.WaitGroup
+	shutdown  chan struct{}
+	jobQueued chan struct{}
+	blobs     storage.BlobStore
+
+	recommendations atomic.Pointer[recommend.Index]
+	stats           statsCache
 }
 
 func openDB(cfg config) (*sql.DB, error) {
@@ -98,8 +129,35 @@ func main() {
 	flag.StringVar(&cfg.smtp.sender, "smtp-sender", os.Getenv("GREENLIGHT_SMTP_SENDER"), "SMTP sender")
 	flag.BoolVar(&cfg.smtp.enable, "smtp-enable", false, "SMTP enable")
 
+	flag.StringVar(&cfg.cursor.secret, "cursor-secret", os.Getenv("GREENLIGHT_CURSOR_SECRET"), "Secret used to sign pagination cursors")
+
+	flag.DurationVar(&cfg.trash.retention, "trash-retention", 30*24*time.Hour, "How long deleted movies are kept before being purged")
+	flag.DurationVar(&cfg.trash.purgeInterval, "trash-purge-interval", time.Hour, "How often expired movies are purged from the trash")
+
+	flag.IntVar(&cfg.jobs.workers, "job-workers", 2, "Number of background job workers")
+
+	flag.StringVar(&cfg.blobs.dir, "blob-dir", "./uploads", "Directory uploaded images are stored in")
+	flag.StringVar(&cfg.blobs.baseURL, "blob-url", "/v1/images", "Base URL uploaded images are served from")
+	flag.DurationVar(&cfg.blobs.cleanupInterval, "blob-cleanup-interval", 24*time.Hour, "How often unreferenced uploads are removed")
+
+	flag.DurationVar(&cfg.recommendations.refreshInterval, "recommendations-refresh-interval", 15*time.Minute, "How often the recommendation index is rebuilt")
+
+	flag.DurationVar(&cfg.stats.cacheTTL, "stats-cache-ttl", 5*time.Minute, "How long catalogue statistics are cached for (0 disables caching)")
+
 	flag.Parse()
 
+	if cfg.cursor.secret == "" {
+		secret := make([]byte, 32)
+
+		_, err := rand.Read(secret)
+		if err != nil {
+			logger.PrintFatal(err, nil)
+		}
+
+		cfg.cursor.secret = string(secret)
+		logger.PrintInfo("no cursor secret configured, pagination cursors will not survive a restart", nil)
+	}
+
 	db, err := openDB(cfg)
 	if err != nil {
 		logger.PrintFatal(err, nil)
@@ -122,13 +180,26 @@ func main() {
 		return time.Now().Unix()
 	}))
 
+	blobs, err := storage.NewFileStore(cfg.blobs.dir, cfg.blobs.baseURL)
+	if err != nil {
+		logger.PrintFatal(err, nil)
+	}
+
 	app := &application{
-		config: cfg,
-		logger: logger,
-		models: data.NewModels(db),
-		mailer: mailer.New(cfg.smtp.host, cfg.smtp.port, cfg.smtp.username, cfg.smtp.password, cfg.smtp.sender, cfg.smtp.enable),
+		config:    cfg,
+		logger:    logger,
+		models:    data.NewModels(db),
+		mailer:    mailer.New(cfg.smtp.host, cfg.smtp.port, cfg.smtp.username, cfg.smtp.password, cfg.smtp.sender, cfg.smtp.enable),
+		shutdown:  make(chan struct{}),
+		jobQueued: make(chan struct{}, 1),
+		blobs:     blobs,
 	}
 
+	app.startTrashPurger()
+	app.startJobWorkers()
+	app.startBlobCleaner()
+	app.startRecommendationIndexer()
+
 	err = app.serve()
 	if err != nil {
 		logger.PrintFatal(err, nil)
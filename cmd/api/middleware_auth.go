@@ -23,6 +23,7 @@ func (app *application) authenticate(next http.Handler) http.Handler {
 
 		headerParts := strings.Split(authorizationHeader, " ")
 		if len(headerParts) != 2 || headerParts[0] != "Bearer" {
+			app.recordAuthFailure(r, "malformed authorization header")
 			app.invalidAuthenticationTokenResponse(w, r)
 			return
 		}
@@ -32,6 +33,7 @@ func (app *application) authenticate(next http.Handler) http.Handler {
 		v := validator.New()
 
 		if data.ValidateTokenPlaintext(v, token); !v.Valid() {
+			app.recordAuthFailure(r, "malformed token")
 			app.invalidAuthenticationTokenResponse(w, r)
 			return
 		}
@@ -40,6 +42,7 @@ func (app *application) authenticate(next http.Handler) http.Handler {
 		if err != nil {
 			switch {
 			case errors.Is(err, data.ErrRecordNotFound):
+				app.recordAuthFailure(r, "invalid or expired token")
 				app.invalidAuthenticationTokenResponse(w, r)
 			default:
 				app.serverErrorResponse(w, r, err)
@@ -59,6 +62,7 @@ func (app *application) requireAuthenticatedUser(next http.HandlerFunc) http.Han
 		user := app.contextGetUser(r)
 
 		if user.IsAnonymous() {
+			app.recordAccessDenied(r, 0, "authentication required")
 			app.authenticationRequiredResponse(w, r)
 			return
 		}
@@ -72,6 +76,7 @@ func (app *application) requireActivatedUser(next http.HandlerFunc) http.Handler
 		user := app.contextGetUser(r)
 
 		if !user.Activated {
+			app.recordAccessDenied(r, user.ID, "account not activated")
 			app.inactiveAccountResponse(w, r)
 			return
 		}
@@ -81,3 +86,22 @@ func (app *application) requireActivatedUser(next http.HandlerFunc) http.Handler
 
 	return app.requireAuthenticatedUser(fn)
 }
+
+// recordAuthFailure audits a request whose bearer token was rejected.
+func (app *application) recordAuthFailure(r *http.Request, reason string) {
+	app.recordAuditEvent(r, data.AuditActionAuthFailed, false, 0, map[string]string{
+		"reason":         reason,
+		"request_method": r.Method,
+		"request_url":    r.URL.String(),
+	})
+}
+
+// recordAccessDenied audits a request turned away for lacking a logged-in or
+// activated user, as requirePermission does for missing permissions.
+func (app *application) recordAccessDenied(r *http.Request, userID int64, reason string) {
+	app.recordAuditEvent(r, data.AuditActionAccessDenied, false, userID, map[string]string{
+		"reason":         reason,
+		"request_method": r.Method,
+		"request_url":    r.URL.String(),
+	})
+}
@@ -1,6 +1,10 @@
 package main
 
-import "net/http"
+import (
+	"net/http"
+
+	"github.com/mrityunjaygr8/greenlight/internal/data"
+)
 
 func (app *application) requirePermission(code string, next http.HandlerFunc) http.HandlerFunc {
 	fn := func(w http.ResponseWriter, r *http.Request) {
@@ -13,6 +17,11 @@ func (app *application) requirePermission(code string, next http.HandlerFunc) ht
 		}
 
 		if !permissions.Include(code) {
+			app.recordAuditEvent(r, data.AuditActionPermissionDenied, false, user.ID, map[string]string{
+				"permission":     code,
+				"request_method": r.Method,
+				"request_url":    r.URL.String(),
+			})
 			app.notPermittedResponse(w, r)
 			return
 		}
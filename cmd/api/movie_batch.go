@@ -0,0 +1,260 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/mrityunjaygr8/greenlight/internal/data"
+	"github.com/mrityunjaygr8/greenlight/internal/validator"
+)
+
+const maxMovieBatchOperations = 100
+
+const (
+	movieBatchAtomic     = "atomic"
+	movieBatchBestEffort = "best_effort"
+)
+
+type movieBatchOperation struct {
+	Op      string          `json:"op"`
+	ID      int64           `json:"id"`
+	Version int32           `json:"version"`
+	Movie   json.RawMessage `json:"movie"`
+}
+
+// movieBatchResult reports the outcome of one operation with the status code
+// and body the single-movie endpoint would have responded with.
+type movieBatchResult struct {
+	Status  int         `json:"status"`
+	Movie   *data.Movie `json:"movie,omitempty"`
+	Message string      `json:"message,omitempty"`
+	Error   interface{} `json:"error,omitempty"`
+}
+
+func (app *application) batchMoviesHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Mode       string                `json:"mode"`
+		Operations []movieBatchOperation `json:"operations"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Mode == "" {
+		input.Mode = movieBatchAtomic
+	}
+
+	v := validator.New()
+
+	v.Check(validator.In(input.Mode, movieBatchAtomic, movieBatchBestEffort), "mode", "must be atomic or best_effort")
+	v.Check(len(input.Operations) > 0, "operations", "must contain at least 1 operation")
+	v.Check(len(input.Operations) <= maxMovieBatchOperations, "operations", fmt.Sprintf("must not contain more than %d operations", maxMovieBatchOperations))
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	atomic := input.Mode == movieBatchAtomic
+
+	results := make([]*movieBatchResult, len(input.Operations))
+	ops := []*data.MovieBatchOp{}
+	indexes := []int{}
+	failed := -1
+
+	// Operations are prepared against the movies as they stand before the
+	// batch, so a movie may only be touched once per batch.
+	seen := make(map[int64]bool)
+
+	for i, operation := range input.Operations {
+		op, result, err := app.prepareMovieBatchOp(operation, seen)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		if result != nil {
+			results[i] = result
+			if failed == -1 {
+				failed = i
+			}
+			continue
+		}
+
+		ops = append(ops, op)
+		indexes = append(indexes, i)
+	}
+
+	if !atomic || failed == -1 {
+		err = app.models.Movies.Batch(ops, atomic, app.contextGetUser(r).ID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		for j, op := range ops {
+			i := indexes[j]
+
+			switch {
+			case errors.Is(op.Err, data.ErrRecordNotFound):
+				results[i] = &movieBatchResult{Status: http.StatusNotFound, Error: notFoundMessage}
+			case errors.Is(op.Err, data.ErrEditConflict):
+				results[i] = &movieBatchResult{Status: http.StatusConflict, Error: editConflictMessage}
+			default:
+				continue
+			}
+
+			if failed == -1 || i < failed {
+				failed = i
+			}
+		}
+	}
+
+	// In atomic mode a single failure means nothing was written, so every
+	// other operation is reported as not applied.
+	committed := !atomic || failed == -1
+
+	for j, op := range ops {
+		i := indexes[j]
+
+		switch {
+		case results[i] != nil:
+		case !committed:
+			results[i] = &movieBatchResult{Status: http.StatusFailedDependency, Error: fmt.Sprintf("not applied because operation %d failed", failed)}
+		case op.Op == data.MovieBatchCreate:
+			results[i] = &movieBatchResult{Status: http.StatusCreated, Movie: op.Movie}
+		case op.Op == data.MovieBatchUpdate:
+			results[i] = &movieBatchResult{Status: http.StatusOK, Movie: op.Movie}
+		default:
+			results[i] = &movieBatchResult{Status: http.StatusOK, Message: "movie moved to trash"}
+		}
+	}
+
+	status := http.StatusOK
+	if failed != -1 {
+		status = http.StatusMultiStatus
+	}
+
+	err = app.writeJSON(w, status, envelope{"committed": committed, "results": results}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// prepareMovieBatchOp checks an operation as the matching single-movie handler
+// would, up to the point of writing. An operation which already fails comes
+// back as a result rather than an op. seen holds the movies earlier operations
+// update or delete, and the operation's own movie is added to it.
+func (app *application) prepareMovieBatchOp(operation movieBatchOperation, seen map[int64]bool) (*data.MovieBatchOp, *movieBatchResult, error) {
+	v := validator.New()
+
+	v.Check(validator.In(operation.Op, data.MovieBatchCreate, data.MovieBatchUpdate, data.MovieBatchDelete), "op", "must be create, update or delete")
+
+	switch operation.Op {
+	case data.MovieBatchCreate:
+		v.Check(operation.ID == 0, "id", "must not be provided")
+		v.Check(operation.Version == 0, "version", "must not be provided")
+		v.Check(operation.Movie != nil, "movie", "must be provided")
+	case data.MovieBatchUpdate:
+		v.Check(operation.ID > 0, "id", "must be provided")
+		v.Check(!seen[operation.ID], "id", "must not be a movie an earlier operation changes")
+		v.Check(operation.Movie != nil, "movie", "must be provided")
+	case data.MovieBatchDelete:
+		v.Check(operation.ID > 0, "id", "must be provided")
+		v.Check(!seen[operation.ID], "id", "must not be a movie an earlier operation changes")
+		v.Check(operation.Movie == nil, "movie", "must not be provided")
+	}
+
+	if operation.Op != data.MovieBatchCreate && operation.ID > 0 {
+		seen[operation.ID] = true
+	}
+
+	if !v.Valid() {
+		return nil, &movieBatchResult{Status: http.StatusUnprocessableEntity, Error: v.Errors}, nil
+	}
+
+	op := &data.MovieBatchOp{Op: operation.Op}
+
+	switch operation.Op {
+	case data.MovieBatchCreate:
+		var input MovieRequest
+
+		err := decodeMovieBatchBody(operation.Movie, &input)
+		if err != nil {
+			return nil, &movieBatchResult{Status: http.StatusBadRequest, Error: err.Error()}, nil
+		}
+
+		op.Movie = &data.Movie{
+			Title:   input.Title,
+			Genres:  input.Genres,
+			Year:    input.Year,
+			Runtime: input.Runtime,
+		}
+
+	case data.MovieBatchUpdate:
+		movie, err := app.models.Movies.Get(operation.ID)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				return nil, &movieBatchResult{Status: http.StatusNotFound, Error: notFoundMessage}, nil
+			default:
+				return nil, nil, err
+			}
+		}
+
+		if operation.Version != 0 && operation.Version != movie.Version {
+			return nil, &movieBatchResult{Status: http.StatusConflict, Error: editConflictMessage}, nil
+		}
+
+		var input movieUpdate
+
+		err = decodeMovieBatchBody(operation.Movie, &input)
+		if err != nil {
+			return nil, &movieBatchResult{Status: http.StatusBadRequest, Error: err.Error()}, nil
+		}
+
+		input.apply(movie)
+		op.Movie = movie
+
+	case data.MovieBatchDelete:
+		op.Movie = &data.Movie{ID: operation.ID, Version: operation.Version}
+		return op, nil, nil
+	}
+
+	data.ValidateMovie(v, op.Movie)
+
+	if v.Valid() {
+		var err error
+
+		op.Movie.Genres, err = app.resolveGenres(v, "genres", op.Movie.Genres)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if !v.Valid() {
+		return nil, &movieBatchResult{Status: http.StatusUnprocessableEntity, Error: v.Errors}, nil
+	}
+
+	return op, nil, nil
+}
+
+// decodeMovieBatchBody decodes the movie of an operation, rejecting unknown
+// fields as readJSON does for a request body.
+func decodeMovieBatchBody(body json.RawMessage, dst interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.DisallowUnknownFields()
+
+	err := dec.Decode(dst)
+	if err != nil {
+		return fmt.Errorf("movie is invalid: %w", err)
+	}
+
+	return nil
+}
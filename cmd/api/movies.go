@@ -1,11 +1,17 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"mime"
 	"net/http"
+	"net/url"
+	"time"
 
 	"github.com/mrityunjaygr8/greenlight/internal/data"
+	"github.com/mrityunjaygr8/greenlight/internal/jsonpatch"
 	"github.com/mrityunjaygr8/greenlight/internal/validator"
 )
 
@@ -40,7 +46,18 @@ func (app *application) createMovieHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	err = app.models.Movies.Insert(movie)
+	movie.Genres, err = app.resolveGenres(v, "genres", movie.Genres)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Movies.Insert(movie, app.contextGetUser(r).ID)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -60,7 +77,26 @@ func (app *application) showMovieHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	movie, err := app.models.Movies.Get(id)
+	app.showMovie(w, r, id)
+}
+
+// showMovie responds with the movie, honouring the fields and include
+// parameters, for any endpoint which has worked out which movie is meant.
+func (app *application) showMovie(w http.ResponseWriter, r *http.Request, id int64) {
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	fields := app.readCSV(qs, "fields", []string{})
+	include := app.readCSV(qs, "include", []string{data.MovieIncludeCredits})
+	languages := app.readLanguages(r, v)
+
+	if data.ValidateMovieFields(v, fields, include); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	movie, err := app.models.Movies.GetFields(id, fields)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -71,12 +107,127 @@ func (app *application) showMovieHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"movie": movie}, nil)
+	err = app.loadMovieRelations(r, fields, include, movie)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.localizeMovies(languages, fields, movie)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	sparse, err := sparseFields(movie, fields, include)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	env := envelope{"movie": sparse}
+
+	// The tag is taken from the finished body, since it varies with far more
+	// than the movie's version. Localizing comes first, so each language gets
+	// its own tag and editing a translation changes it.
+	etag, err := movieETag(movie, env)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// The title depends on the client's language preferences.
+	w.Header().Add("Vary", "Accept-Language")
+
+	if app.notModified(w, r, etag) {
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, env, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
 }
 
+// loadMovieRelations fills in the computed fields and embedded resources the
+// client asked for, skipping the queries for anything left out.
+func (app *application) loadMovieRelations(r *http.Request, fields, include []string, movies ...*data.Movie) error {
+	if len(movies) == 0 {
+		return nil
+	}
+
+	if data.HasField(fields, "in_watchlist") {
+		err := app.setInWatchlist(r, movies...)
+		if err != nil {
+			return err
+		}
+	}
+
+	if data.HasField(fields, "poster") || data.HasField(fields, "backdrop") {
+		err := app.setMovieImages(fields, movies...)
+		if err != nil {
+			return err
+		}
+	}
+
+	if data.HasField(fields, "external_ids") {
+		ids := make([]int64, len(movies))
+		for i, movie := range movies {
+			ids[i] = movie.ID
+		}
+
+		externalIDs, err := app.models.ExternalIDs.GetForMovies(ids)
+		if err != nil {
+			return err
+		}
+
+		for _, movie := range movies {
+			movie.ExternalIDs = externalIDs[movie.ID]
+		}
+	}
+
+	if validator.In(data.MovieIncludeTranslations, include...) || validator.In(data.MovieIncludeReleases, include...) {
+		err := app.setMovieLocalizations(include, movies...)
+		if err != nil {
+			return err
+		}
+	}
+
+	if validator.In(data.MovieIncludeCollection, include...) {
+		ids := make([]int64, len(movies))
+		for i, movie := range movies {
+			ids[i] = movie.ID
+		}
+
+		collections, err := app.models.Collections.GetForMovies(ids)
+		if err != nil {
+			return err
+		}
+
+		for _, movie := range movies {
+			movie.Collection = collections[movie.ID]
+		}
+	}
+
+	if validator.In(data.MovieIncludeCredits, include...) {
+		ids := make([]int64, len(movies))
+		for i, movie := range movies {
+			ids[i] = movie.ID
+		}
+
+		credits, err := app.models.Credits.GetForMovies(ids)
+		if err != nil {
+			return err
+		}
+
+		for _, movie := range movies {
+			movie.Credits = credits[movie.ID]
+		}
+	}
+
+	return nil
+}
+
 func (app *application) updateMovieHandler(w http.ResponseWriter, r *http.Request) {
 	id, err := app.readIDParam(r)
 	if err != nil {
@@ -95,33 +246,43 @@ func (app *application) updateMovieHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	var input struct {
-		Title   *string       `json:"title"`
-		Year    *int32        `json:"year"`
-		Runtime *data.Runtime `json:"runtime"`
-		Genres  []string      `json:"genres"`
-	}
-	err = app.readJSON(w, r, &input)
-	if err != nil {
-		app.badRequestResponse(w, r, err)
+	if app.moviePreconditionFailed(w, r, movie) {
 		return
 	}
 
-	if input.Year != nil {
-		movie.Year = *input.Year
+	// A missing Content-Type is treated as plain JSON, which is what clients
+	// sent before the patch formats were supported.
+	contentType := r.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "application/json"
 	}
 
-	if input.Title != nil {
-		movie.Title = *input.Title
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		app.unsupportedMediaTypeResponse(w, r)
+		return
 	}
 
-	if input.Runtime != nil {
-		movie.Runtime = *input.Runtime
+	switch mediaType {
+	case "application/json":
+		err = app.readMovieUpdate(w, r, movie)
+	case "application/merge-patch+json", "application/json-patch+json":
+		err = app.patchMovie(w, r, mediaType, movie)
+	default:
+		app.unsupportedMediaTypeResponse(w, r)
+		return
 	}
 
-	if input.Genres != nil {
-		movie.Genres = input.Genres
+	if err != nil {
+		switch {
+		case errors.Is(err, jsonpatch.ErrTestFailed):
+			app.patchTestFailedResponse(w, r, err)
+		default:
+			app.badRequestResponse(w, r, err)
+		}
+		return
 	}
+
 	v := validator.New()
 
 	data.ValidateMovie(v, movie)
@@ -130,9 +291,23 @@ func (app *application) updateMovieHandler(w http.ResponseWriter, r *http.Reques
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
-	err = app.models.Movies.Update(movie)
+
+	movie.Genres, err = app.resolveGenres(v, "genres", movie.Genres)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Movies.Update(movie, app.contextGetUser(r).ID)
 	if err != nil {
 		switch {
+		case errors.Is(err, data.ErrEditConflict) && r.Header.Get("If-Match") != "":
+			app.preconditionFailedResponse(w, r)
 		case errors.Is(err, data.ErrEditConflict):
 			app.editConflictResponse(w, r)
 		default:
@@ -141,15 +316,124 @@ func (app *application) updateMovieHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	env := envelope{"movie": movie}
+
+	etag, err := movieETag(movie, env)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
 	headers := make(http.Header)
 	headers.Set("Location", fmt.Sprintf("/v1/movies/%d", movie.ID))
-	err = app.writeJSON(w, http.StatusOK, envelope{"movie": movie}, headers)
+	headers.Set("ETag", etag)
+	err = app.writeJSON(w, http.StatusOK, env, headers)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
 
 }
 
+// movieUpdate is a plain JSON update to a movie. Fields left out of the body
+// stay nil and are not changed.
+type movieUpdate struct {
+	Title   *string       `json:"title"`
+	Year    *int32        `json:"year"`
+	Runtime *data.Runtime `json:"runtime"`
+	Genres  []string      `json:"genres"`
+}
+
+func (input movieUpdate) apply(movie *data.Movie) {
+	if input.Year != nil {
+		movie.Year = *input.Year
+	}
+
+	if input.Title != nil {
+		movie.Title = *input.Title
+	}
+
+	if input.Runtime != nil {
+		movie.Runtime = *input.Runtime
+	}
+
+	if input.Genres != nil {
+		movie.Genres = input.Genres
+	}
+}
+
+// readMovieUpdate applies a plain JSON body to movie, changing only the fields
+// which are present.
+func (app *application) readMovieUpdate(w http.ResponseWriter, r *http.Request, movie *data.Movie) error {
+	var input movieUpdate
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		return err
+	}
+
+	input.apply(movie)
+
+	return nil
+}
+
+// patchMovie applies a JSON Patch or JSON Merge Patch body to the editable
+// fields of movie, which are patched in the same shape as a create request.
+func (app *application) patchMovie(w http.ResponseWriter, r *http.Request, mediaType string, movie *data.Movie) error {
+	doc, err := json.Marshal(MovieRequest{
+		Title:   movie.Title,
+		Year:    movie.Year,
+		Runtime: movie.Runtime,
+		Genres:  movie.Genres,
+	})
+	if err != nil {
+		return err
+	}
+
+	var patched []byte
+
+	switch mediaType {
+	case "application/merge-patch+json":
+		var patch json.RawMessage
+
+		err = app.readJSON(w, r, &patch)
+		if err != nil {
+			return err
+		}
+
+		patched, err = jsonpatch.MergePatch(doc, patch)
+	default:
+		var patch jsonpatch.Patch
+
+		err = app.readJSON(w, r, &patch)
+		if err != nil {
+			return err
+		}
+
+		patched, err = patch.Apply(doc)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	var input MovieRequest
+
+	dec := json.NewDecoder(bytes.NewReader(patched))
+	dec.DisallowUnknownFields()
+
+	err = dec.Decode(&input)
+	if err != nil {
+		return fmt.Errorf("patched movie is invalid: %w", err)
+	}
+
+	movie.Title = input.Title
+	movie.Year = input.Year
+	movie.Runtime = input.Runtime
+	movie.Genres = input.Genres
+
+	return nil
+}
+
 func (app *application) deleteMovieHandler(w http.ResponseWriter, r *http.Request) {
 	id, err := app.readIDParam(r)
 	if err != nil {
@@ -157,11 +441,36 @@ func (app *application) deleteMovieHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	err = app.models.Movies.Delete(id)
+	// With If-Match the delete only goes ahead for the version the client
+	// saw, so an edit landing between the check and the delete is not lost.
+	var version int32
+
+	if r.Header.Get("If-Match") != "" {
+		movie, err := app.models.Movies.Get(id)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				app.notFoundResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		if app.moviePreconditionFailed(w, r, movie) {
+			return
+		}
+
+		version = movie.Version
+	}
+
+	err = app.models.Movies.Delete(id, version)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
 			app.notFoundResponse(w, r)
+		case errors.Is(err, data.ErrEditConflict):
+			app.preconditionFailedResponse(w, r)
 		default:
 			app.serverErrorResponse(w, r, err)
 		}
@@ -169,7 +478,7 @@ func (app *application) deleteMovieHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"message": "movie deleted successfully"}, nil)
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "movie moved to trash"}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -177,36 +486,178 @@ func (app *application) deleteMovieHandler(w http.ResponseWriter, r *http.Reques
 
 func (app *application) listMoviesHandler(w http.ResponseWriter, r *http.Request) {
 	var input struct {
-		Title  string
-		Genres []string
+		data.MovieFilters
 		data.Filters
+		Facets    []string
+		Fields    []string
+		Include   []string
+		Languages []string
 	}
 
 	v := validator.New()
 
 	qs := r.URL.Query()
 
-	input.Title = app.readString(qs, "title", "")
-	input.Genres = app.readCSV(qs, "genres", []string{})
+	input.MovieFilters = app.readMovieFilters(qs, v)
+
+	input.Facets = app.readCSV(qs, "facets", []string{})
+	input.Fields = app.readCSV(qs, "fields", []string{})
+	input.Include = app.readCSV(qs, "include", []string{})
+	input.Languages = app.readLanguages(r, v)
 
 	input.Filters.Page = app.readInt(qs, "page", 1, v)
 	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
 
 	input.Filters.Sort = app.readString(qs, "sort", "id")
-	input.Filters.SortSafelist = []string{"id", "title", "year", "runtime", "-id", "-title", "-year", "-runtime"}
+	input.Filters.SortSafelist = []string{"id", "title", "year", "runtime", "average_rating", "rating_count", "relevance", "-id", "-title", "-year", "-runtime", "-average_rating", "-rating_count"}
+
+	input.Filters.Cursor = app.readString(qs, "cursor", "")
+	input.Filters.CursorSecret = []byte(app.config.cursor.secret)
+	input.Filters.IncludeTotal = app.readBool(qs, "include_total", input.Filters.Cursor == "", v)
+
+	data.ValidateMovieFilters(v, input.MovieFilters)
+	data.ValidateFacets(v, input.Facets)
+	data.ValidateMovieFields(v, input.Fields, input.Include)
+
+	if input.Filters.Sort == "relevance" {
+		v.Check(input.Title != "", "sort", "relevance sort requires a title search")
+		v.Check(input.Filters.Cursor == "", "cursor", "must not be used with relevance sort")
+	}
 
 	if data.ValidateFilters(v, input.Filters); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	movies, metadata, err := app.models.Movies.GetAll(input.Title, input.Genres, input.Filters)
+	err := app.resolveMovieFilterGenres(v, &input.MovieFilters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	movies, metadata, err := app.models.Movies.GetAll(input.MovieFilters, input.Filters, input.Fields)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.loadMovieRelations(r, input.Fields, input.Include, movies...)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.localizeMovies(input.Languages, input.Fields, movies...)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	sparse := make([]interface{}, len(movies))
+	for i, movie := range movies {
+		sparse[i], err = sparseFields(movie, input.Fields, input.Include)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	env := envelope{"movies": sparse, "metadata": metadata}
+
+	if len(input.Facets) > 0 {
+		env["facets"], err = app.models.Movies.Facets(input.MovieFilters, input.Facets)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	etag, err := bodyETag(env)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	w.Header().Add("Vary", "Accept-Language")
+
+	if app.notModified(w, r, etag) {
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// readMovieFilters reads the filter parameters shared by the movie list and
+// export endpoints.
+func (app *application) readMovieFilters(qs url.Values, v *validator.Validator) data.MovieFilters {
+	var f data.MovieFilters
+
+	f.Title = app.readString(qs, "title", "")
+	f.Language = app.readString(qs, "search_lang", "")
+	f.Fuzzy = app.readBool(qs, "fuzzy", false, v)
+	f.Genres = app.readCSV(qs, "genres", []string{})
+	f.GenresAny = app.readCSV(qs, "genres_any", []string{})
+	f.GenresExclude = app.readCSV(qs, "genres_exclude", []string{})
+	f.YearMin = app.readInt(qs, "year_min", 0, v)
+	f.YearMax = app.readInt(qs, "year_max", 0, v)
+	f.RuntimeMin = app.readInt(qs, "runtime_min", 0, v)
+	f.RuntimeMax = app.readInt(qs, "runtime_max", 0, v)
+	f.CreatedAfter = app.readTime(qs, "created_after", time.Time{}, v)
+	f.IDs = app.readInt64CSV(qs, "ids", []int64{}, v)
+	f.PersonID = int64(app.readInt(qs, "person_id", 0, v))
+	f.CollectionID = int64(app.readInt(qs, "collection_id", 0, v))
+	f.Director = app.readString(qs, "director", "")
+
+	return f
+}
+
+// resolveMovieFilterGenres replaces the genre names in f with their canonical
+// slugs, adding a validation error for any which are unknown.
+func (app *application) resolveMovieFilterGenres(v *validator.Validator, f *data.MovieFilters) error {
+	var err error
+
+	f.Genres, err = app.resolveGenres(v, "genres", f.Genres)
+	if err != nil {
+		return err
+	}
+
+	f.GenresAny, err = app.resolveGenres(v, "genres_any", f.GenresAny)
+	if err != nil {
+		return err
+	}
+
+	f.GenresExclude, err = app.resolveGenres(v, "genres_exclude", f.GenresExclude)
+	return err
+}
+
+func (app *application) autocompleteMoviesHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	q := app.readString(qs, "q", "")
+	limit := app.readInt(qs, "limit", 10, v)
+
+	if data.ValidateAutocomplete(v, q, limit); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	suggestions, err := app.models.Movies.Autocomplete(q, limit)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"movies": movies, "metadata": metadata}, nil)
+	err = app.writeJSON(w, http.StatusOK, envelope{"suggestions": suggestions}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
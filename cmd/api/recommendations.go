@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mrityunjaygr8/greenlight/internal/data"
+	"github.com/mrityunjaygr8/greenlight/internal/recommend"
+	"github.com/mrityunjaygr8/greenlight/internal/validator"
+)
+
+// recommendationLoadTimeout bounds loading the whole catalogue into the
+// recommendation index.
+const recommendationLoadTimeout = time.Minute
+
+// maxRecommendationSignals is how many of a user's most recently rated or
+// watched movies their recommendations are based on.
+const maxRecommendationSignals = 200
+
+// movieMatch is a movie returned by the similar and recommendations endpoints,
+// along with how well it matched.
+type movieMatch struct {
+	Score float64     `json:"score"`
+	Movie interface{} `json:"movie"`
+}
+
+// startRecommendationIndexer builds the recommendation index in the
+// background and rebuilds it once per refresh interval, so that new and
+// edited movies are picked up. Until the first build finishes the index is
+// empty and the endpoints return no matches.
+func (app *application) startRecommendationIndexer() {
+	app.recommendations.Store(recommend.New(nil))
+
+	app.background(app.refreshRecommendations)
+	app.every(app.config.recommendations.refreshInterval, app.refreshRecommendations)
+}
+
+func (app *application) refreshRecommendations() {
+	ctx, cancel := context.WithTimeout(context.Background(), recommendationLoadTimeout)
+	defer cancel()
+
+	features, err := app.models.Movies.GetFeatures(ctx)
+	if err != nil {
+		app.logger.PrintError(err, nil)
+		return
+	}
+
+	index := recommend.New(features)
+	app.recommendations.Store(index)
+
+	app.logger.PrintInfo("rebuilt recommendation index", map[string]string{
+		"movies": strconv.Itoa(index.Len()),
+	})
+}
+
+func (app *application) listSimilarMoviesHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	v := validator.New()
+
+	limit, fields, include, languages := app.readMovieMatchParams(r, v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	index := app.recommendations.Load()
+
+	// Movies added since the last rebuild are not in the index yet, so only
+	// those the database does not know either are missing.
+	if !index.Has(id) {
+		_, err := app.models.Movies.GetFields(id, []string{"id"})
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				app.notFoundResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+	}
+
+	matches, err := app.loadMovieMatches(r, index.Similar(id, limit), fields, include, languages)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	w.Header().Add("Vary", "Accept-Language")
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"similar": matches}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listRecommendationsHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+
+	limit, fields, include, languages := app.readMovieMatchParams(r, v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	signals, err := app.models.Movies.GetUserSignals(app.contextGetUser(r).ID, maxRecommendationSignals)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	index := app.recommendations.Load()
+
+	matches, err := app.loadMovieMatches(r, index.Recommend(signals, limit), fields, include, languages)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	w.Header().Add("Vary", "Accept-Language")
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"recommendations": matches}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// readMovieMatchParams reads the parameters shared by the similar and
+// recommendations endpoints.
+func (app *application) readMovieMatchParams(r *http.Request, v *validator.Validator) (int, []string, []string, []string) {
+	qs := r.URL.Query()
+
+	limit := app.readInt(qs, "limit", 10, v)
+	fields := app.readCSV(qs, "fields", []string{})
+	include := app.readCSV(qs, "include", []string{})
+	languages := app.readLanguages(r, v)
+
+	v.Check(limit > 0, "limit", "must be greater than zero")
+	v.Check(limit <= 50, "limit", "must be a maximum of 50")
+
+	data.ValidateMovieFields(v, fields, include)
+
+	return limit, fields, include, languages
+}
+
+// loadMovieMatches fetches the matched movies, keeping the index's order.
+// Movies trashed since the index was built are dropped.
+func (app *application) loadMovieMatches(r *http.Request, matches []recommend.Match, fields, include, languages []string) ([]movieMatch, error) {
+	results := []movieMatch{}
+
+	// An empty IDs filter would match every movie.
+	if len(matches) == 0 {
+		return results, nil
+	}
+
+	ids := make([]int64, len(matches))
+	for i, match := range matches {
+		ids[i] = match.MovieID
+	}
+
+	filters := data.Filters{
+		Page:         1,
+		PageSize:     len(ids),
+		Sort:         "id",
+		SortSafelist: []string{"id"},
+	}
+
+	movies, _, err := app.models.Movies.GetAll(data.MovieFilters{IDs: ids}, filters, fields)
+	if err != nil {
+		return nil, err
+	}
+
+	err = app.loadMovieRelations(r, fields, include, movies...)
+	if err != nil {
+		return nil, err
+	}
+
+	err = app.localizeMovies(languages, fields, movies...)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[int64]*data.Movie, len(movies))
+	for _, movie := range movies {
+		byID[movie.ID] = movie
+	}
+
+	for _, match := range matches {
+		movie, ok := byID[match.MovieID]
+		if !ok {
+			continue
+		}
+
+		sparse, err := sparseFields(movie, fields, include)
+		if err != nil {
+			return nil, err
+		}
+
+		results = append(results, movieMatch{
+			Score: math.Round(match.Score*1000) / 1000,
+			Movie: sparse,
+		})
+	}
+
+	return results, nil
+}
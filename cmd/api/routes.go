@@ -14,18 +14,124 @@ func (app *application) routes() http.Handler {
 	router.MethodNotAllowed = http.HandlerFunc(app.methodNotAllowedResponse)
 
 	router.HandlerFunc(http.MethodGet, "/v1/healthcheck", app.healthcheckHandler)
-	router.HandlerFunc(http.MethodGet, "/v1/movies/:id", app.requirePermission("movies:read", app.showMovieHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/movies/:id", app.staticSegments("id", map[string]http.HandlerFunc{
+		"autocomplete": app.requirePermission("movies:read", app.autocompleteMoviesHandler),
+		"duplicates":   app.requirePermission("movies:merge", app.listDuplicateMoviesHandler),
+		"export":       app.requirePermission("movies:read", app.exportMoviesHandler),
+		"lookup":       app.requirePermission("movies:read", app.lookupMovieHandler),
+		"trash":        app.requirePermission("movies:write", app.listTrashHandler),
+	}, app.requirePermission("movies:read", app.showMovieHandler)))
 	router.HandlerFunc(http.MethodPost, "/v1/movies", app.requirePermission("movies:write", app.createMovieHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/movies/:id", app.staticSegments("id", map[string]http.HandlerFunc{
+		"batch":  app.requirePermission("movies:write", app.batchMoviesHandler),
+		"import": app.requirePermission("movies:write", app.importMoviesHandler),
+	}, app.methodNotAllowedResponse))
 	router.HandlerFunc(http.MethodGet, "/v1/movies", app.requirePermission("movies:read", app.listMoviesHandler))
 	router.HandlerFunc(http.MethodDelete, "/v1/movies/:id", app.requirePermission("movies:read", app.deleteMovieHandler))
 	router.HandlerFunc(http.MethodPatch, "/v1/movies/:id", app.requirePermission("movies:read", app.updateMovieHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/movies/:id/restore", app.requirePermission("movies:write", app.restoreMovieHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/movies/:id/purge", app.requirePermission("movies:write", app.purgeMovieHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/movies/:id/merge", app.requirePermission("movies:merge", app.mergeMovieHandler))
+
+	router.HandlerFunc(http.MethodPut, "/v1/movies/:id/external_ids", app.requirePermission("movies:write", app.putMovieExternalIDsHandler))
+
+	router.HandlerFunc(http.MethodPut, "/v1/movies/:id/translations/:lang", app.requirePermission("movies:write", app.putMovieTranslationHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/movies/:id/translations/:lang", app.requirePermission("movies:write", app.deleteMovieTranslationHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/movies/:id/releases", app.requirePermission("movies:write", app.putMovieReleasesHandler))
+
+	router.HandlerFunc(http.MethodPut, "/v1/movies/:id/images/:kind", app.requirePermission("movies:write", app.putMovieImageHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/movies/:id/images/:kind", app.requirePermission("movies:write", app.deleteMovieImageHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/movies/:id/revisions", app.requirePermission("movies:read", app.listMovieRevisionsHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/movies/:id/revisions/:version", app.requirePermission("movies:read", app.showMovieRevisionHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/movies/:id/revisions/:version/revert", app.requirePermission("movies:write", app.revertMovieHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/movies/:id/similar", app.requirePermission("movies:read", app.listSimilarMoviesHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/movies/:id/reviews", app.requirePermission("movies:read", app.listMovieReviewsHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/movies/:id/reviews", app.requirePermission("movies:read", app.createMovieReviewHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/reviews/:id", app.requireActivatedUser(app.updateReviewHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/reviews/:id", app.requireActivatedUser(app.deleteReviewHandler))
+
+	router.HandlerFunc(http.MethodPost, "/v1/movies/:id/credits", app.requirePermission("movies:write", app.createMovieCreditHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/movies/:id/credits/:person_id/:role", app.requirePermission("movies:write", app.deleteMovieCreditHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/genres", app.requirePermission("movies:read", app.listGenresHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/genres", app.requirePermission("genres:write", app.createGenreHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/genres/:id", app.requirePermission("genres:write", app.updateGenreHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/genres/:id", app.requirePermission("genres:write", app.deleteGenreHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/genres/:id/merge", app.requirePermission("genres:write", app.mergeGenreHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/people", app.requirePermission("movies:read", app.listPeopleHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/people", app.requirePermission("movies:write", app.createPersonHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/people/:id", app.requirePermission("movies:read", app.showPersonHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/people/:id", app.requirePermission("movies:write", app.updatePersonHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/people/:id", app.requirePermission("movies:write", app.deletePersonHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/collections", app.requirePermission("movies:read", app.listCollectionsHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/collections", app.requirePermission("movies:write", app.createCollectionHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/collections/:id", app.requirePermission("movies:read", app.showCollectionHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/collections/:id", app.requirePermission("movies:write", app.updateCollectionHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/collections/:id", app.requirePermission("movies:write", app.deleteCollectionHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/collections/:id/movies/:movie_id", app.requirePermission("movies:write", app.putCollectionMovieHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/collections/:id/movies/:movie_id", app.requirePermission("movies:write", app.deleteCollectionMovieHandler))
 
 	router.HandlerFunc(http.MethodPost, "/v1/users", app.registerUserHandler)
 	router.HandlerFunc(http.MethodPut, "/v1/users/activated", app.activateUserHandler)
 
+	router.HandlerFunc(http.MethodGet, "/v1/users/me/watchlist", app.requirePermission("movies:read", app.listWatchlistHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/users/me/watchlist/:movie_id", app.requirePermission("movies:read", app.showWatchlistEntryHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/users/me/watchlist/:movie_id", app.requirePermission("movies:read", app.putWatchlistEntryHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/users/me/watchlist/:movie_id", app.requirePermission("movies:read", app.deleteWatchlistEntryHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/users/me/history", app.requirePermission("movies:read", app.listHistoryHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/users/me/history", app.requirePermission("movies:read", app.createHistoryEntryHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/users/me/recommendations", app.requirePermission("movies:read", app.listRecommendationsHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/users/me/lists", app.requirePermission("movies:read", app.listMyListsHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/lists", app.requirePermission("movies:read", app.listPublicListsHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/lists", app.requirePermission("movies:read", app.createListHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/lists/:id", app.requirePermission("movies:read", app.showListHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/lists/:id", app.requirePermission("movies:read", app.updateListHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/lists/:id", app.requirePermission("movies:read", app.deleteListHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/lists/:id/entries", app.requirePermission("movies:read", app.addListEntriesHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/lists/:id/entries/:movie_id", app.requirePermission("movies:read", app.deleteListEntryHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/lists/:id/order", app.requirePermission("movies:read", app.reorderListHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/shared-lists/:slug", app.showSharedListHandler)
+
 	router.HandlerFunc(http.MethodPost, "/v1/tokens/authenticate", app.createAuthenticationTokenHandler)
 
+	router.HandlerFunc(http.MethodGet, "/v1/jobs/:id", app.requirePermission("movies:write", app.showJobHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/jobs/:id/cancel", app.requirePermission("movies:write", app.cancelJobHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/jobs/:id/resume", app.requirePermission("movies:write", app.resumeJobHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/stats/movies", app.requirePermission("stats:read", app.showMovieStatsHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/audit", app.requirePermission("audit:read", app.listAuditEventsHandler))
+
+	// Stores which keep blobs locally serve them too; others hand out URLs
+	// pointing elsewhere.
+	if blobs, ok := app.blobs.(http.Handler); ok {
+		router.Handler(http.MethodGet, "/v1/images/*key", http.StripPrefix("/v1/images", blobs))
+	}
+
 	router.Handler(http.MethodGet, "/debug/vars", expvar.Handler())
 
 	return app.metrics(app.rateLimit(app.rateLimit(app.authenticate(router))))
 }
+
+// staticSegments lets fixed paths such as /v1/movies/autocomplete share a
+// segment with a wildcard like /v1/movies/:id, which httprouter does not allow
+// to be registered separately. Requests whose param matches one of the static
+// names go to that handler, and everything else falls through to next.
+func (app *application) staticSegments(param string, static map[string]http.HandlerFunc, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		params := httprouter.ParamsFromContext(r.Context())
+
+		if handler, ok := static[params.ByName(param)]; ok {
+			handler(w, r)
+			return
+		}
+
+		next(w, r)
+	}
+}
@@ -43,6 +43,8 @@ func (app *application) serve() error {
 			shutdownError <- err
 		}
 
+		close(app.shutdown)
+
 		app.logger.PrintInfo("completing background tasks", map[string]string{
 			"addr": srv.Addr,
 		})
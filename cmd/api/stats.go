@@ -0,0 +1,96 @@
+package main
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/mrityunjaygr8/greenlight/internal/data"
+	"github.com/mrityunjaygr8/greenlight/internal/validator"
+)
+
+// statsCache keeps computed movie statistics, one entry per growth period,
+// for the configured TTL. Computing them reads the whole catalogue, so each
+// entry's lock is held while it is recomputed: concurrent requests for the
+// same period wait for the one computation instead of each starting their
+// own, while requests for other periods go ahead. mu only guards the map.
+type statsCache struct {
+	mu      sync.Mutex
+	entries map[string]*statsCacheEntry
+}
+
+type statsCacheEntry struct {
+	mu      sync.Mutex
+	stats   *data.MovieStats
+	expires time.Time
+}
+
+// entry returns the cache entry for the period, adding an empty one if there
+// is none yet.
+func (c *statsCache) entry(period string) *statsCacheEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.entries == nil {
+		c.entries = make(map[string]*statsCacheEntry)
+	}
+
+	entry, ok := c.entries[period]
+	if !ok {
+		entry = &statsCacheEntry{}
+		c.entries[period] = entry
+	}
+
+	return entry
+}
+
+// movieStats returns the statistics for the period, computing them afresh if
+// the cached copy has expired. A TTL of zero turns the cache off.
+func (app *application) movieStats(period string) (*data.MovieStats, error) {
+	ttl := app.config.stats.cacheTTL
+
+	if ttl <= 0 {
+		return app.models.Movies.Stats(period)
+	}
+
+	entry := app.stats.entry(period)
+
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+
+	if entry.stats != nil && time.Now().Before(entry.expires) {
+		return entry.stats, nil
+	}
+
+	stats, err := app.models.Movies.Stats(period)
+	if err != nil {
+		return nil, err
+	}
+
+	entry.stats = stats
+	entry.expires = time.Now().Add(ttl)
+
+	return stats, nil
+}
+
+func (app *application) showMovieStatsHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+
+	period := app.readString(r.URL.Query(), "period", "month")
+
+	if data.ValidateStatsPeriod(v, period); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	stats, err := app.movieStats(period)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"stats": stats}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -35,6 +35,7 @@ func (app *application) createAuthenticationTokenHandler(w http.ResponseWriter,
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
+			app.recordAuditEvent(r, data.AuditActionLogin, false, 0, map[string]string{"email": input.Email, "reason": "unknown email"})
 			app.invalidCredentialsResponse(w, r)
 		default:
 			app.serverErrorResponse(w, r, err)
@@ -49,6 +50,7 @@ func (app *application) createAuthenticationTokenHandler(w http.ResponseWriter,
 	}
 
 	if !match {
+		app.recordAuditEvent(r, data.AuditActionLogin, false, user.ID, map[string]string{"email": input.Email, "reason": "password mismatch"})
 		app.invalidCredentialsResponse(w, r)
 		return
 	}
@@ -59,6 +61,10 @@ func (app *application) createAuthenticationTokenHandler(w http.ResponseWriter,
 		return
 	}
 
+	app.recordAuditEvent(r, data.AuditActionLogin, true, user.ID, map[string]string{"email": input.Email})
+
+	app.recordAuditEvent(r, data.AuditActionTokenIssued, true, user.ID, map[string]string{"scope": data.ScopeAuthentication})
+
 	err = app.writeJSON(w, http.StatusCreated, envelope{"token": token}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
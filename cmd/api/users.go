@@ -63,12 +63,16 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	app.recordAuditEvent(r, data.AuditActionUserRegistered, true, user.ID, map[string]string{"email": user.Email})
+
 	token, err := app.models.Tokens.New(user.ID, TOKEN_ACTIVATION_LIFECYCLE, data.ScopeActivation)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
+	app.recordAuditEvent(r, data.AuditActionTokenIssued, true, user.ID, map[string]string{"scope": data.ScopeActivation})
+
 	app.background(func() {
 		data := map[string]string{
 			"activationToken": token.Plaintext,
@@ -109,6 +113,7 @@ func (app *application) activateUserHandler(w http.ResponseWriter, r *http.Reque
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
+			app.recordAuditEvent(r, data.AuditActionUserActivated, false, 0, map[string]string{"reason": "invalid or expired token"})
 			v.AddError("token", "invalid or expired activation token")
 			app.failedValidationResponse(w, r, v.Errors)
 		default:
@@ -126,12 +131,16 @@ func (app *application) activateUserHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	app.recordAuditEvent(r, data.AuditActionUserActivated, true, user.ID, nil)
+
 	err = app.models.Tokens.DeleteAllForUser(data.ScopeActivation, user.ID)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
+	app.recordAuditEvent(r, data.AuditActionTokenRevoked, true, user.ID, map[string]string{"scope": data.ScopeActivation})
+
 	err = app.writeJSON(w, http.StatusOK, envelope{"user": user}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
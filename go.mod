@@ -9,6 +9,7 @@ require (
 	github.com/julienschmidt/httprouter v1.3.0
 	github.com/lib/pq v1.10.9
 	golang.org/x/crypto v0.8.0
+	golang.org/x/image v0.18.0
 	golang.org/x/time v0.3.0
 )
 
@@ -0,0 +1,120 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const (
+	AuditActionLogin            = "login"
+	AuditActionTokenIssued      = "token_issued"
+	AuditActionTokenRevoked     = "token_revoked"
+	AuditActionPermissionDenied = "permission_denied"
+	AuditActionUserRegistered   = "user_registered"
+	AuditActionUserActivated    = "user_activated"
+	AuditActionAuthFailed       = "authentication_failed"
+	AuditActionAccessDenied     = "access_denied"
+)
+
+var AuditActions = []string{
+	AuditActionLogin,
+	AuditActionTokenIssued,
+	AuditActionTokenRevoked,
+	AuditActionPermissionDenied,
+	AuditActionUserRegistered,
+	AuditActionUserActivated,
+	AuditActionAuthFailed,
+	AuditActionAccessDenied,
+}
+
+type AuditEvent struct {
+	ID        int64             `json:"id"`
+	CreatedAt time.Time         `json:"created_at"`
+	UserID    int64             `json:"user_id,omitempty"`
+	Action    string            `json:"action"`
+	Success   bool              `json:"success"`
+	IPAddress string            `json:"ip_address"`
+	Details   map[string]string `json:"details,omitempty"`
+}
+
+type AuditModelInterface interface {
+	Insert(event *AuditEvent) error
+	GetAll(userID int64, action string, filters Filters) ([]*AuditEvent, Metadata, error)
+}
+
+type AuditModel struct {
+	DB *sql.DB
+}
+
+func (m AuditModel) Insert(event *AuditEvent) error {
+	details, err := json.Marshal(event.Details)
+	if err != nil {
+		return err
+	}
+
+	if event.Details == nil {
+		details = []byte("{}")
+	}
+
+	query := `
+INSERT INTO audit_events (user_id, action, success, ip_address, details)
+VALUES (NULLIF($1, 0), $2, $3, $4, $5)
+RETURNING id, created_at`
+
+	args := []interface{}{event.UserID, event.Action, event.Success, event.IPAddress, details}
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&event.ID, &event.CreatedAt)
+}
+
+func (m AuditModel) GetAll(userID int64, action string, filters Filters) ([]*AuditEvent, Metadata, error) {
+	query := fmt.Sprintf(`SELECT count(*) OVER(), id, created_at, COALESCE(user_id, 0), action, success, ip_address, details
+FROM audit_events
+WHERE (user_id = $1 OR $1 = 0)
+AND (action = $2 OR $2 = '')
+ORDER BY %s %s, id DESC
+LIMIT $3 OFFSET $4`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID, action, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	defer rows.Close()
+
+	totalRecords := 0
+	events := []*AuditEvent{}
+
+	for rows.Next() {
+		var event AuditEvent
+		var details []byte
+
+		err := rows.Scan(&totalRecords, &event.ID, &event.CreatedAt, &event.UserID, &event.Action, &event.Success, &event.IPAddress, &details)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		err = json.Unmarshal(details, &event.Details)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		events = append(events, &event)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return events, metadata, nil
+}
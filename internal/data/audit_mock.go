@@ -0,0 +1,11 @@
+package data
+
+type MockAuditModel struct{}
+
+func (m MockAuditModel) Insert(event *AuditEvent) error {
+	return nil
+}
+
+func (m MockAuditModel) GetAll(userID int64, action string, filters Filters) ([]*AuditEvent, Metadata, error) {
+	return nil, Metadata{}, nil
+}
@@ -0,0 +1,355 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/mrityunjaygr8/greenlight/internal/validator"
+)
+
+var ErrDuplicateCollectionPart = errors.New("duplicate collection part")
+
+// Collection is a series of movies, such as a franchise or a trilogy. Each
+// movie belongs to at most one collection, where it has a part number giving
+// its place in the series.
+type Collection struct {
+	ID        int64              `json:"id"`
+	CreatedAt time.Time          `json:"-"`
+	Name      string             `json:"name"`
+	Overview  string             `json:"overview,omitempty"`
+	Movies    []*CollectionEntry `json:"movies,omitempty"`
+	Version   int32              `json:"version"`
+}
+
+type CollectionEntry struct {
+	Part  int32  `json:"part"`
+	Movie *Movie `json:"movie"`
+}
+
+// MovieCollection is the collection a movie belongs to, as embedded in the
+// movie.
+type MovieCollection struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+	Part int32  `json:"part"`
+}
+
+type CollectionModelInterface interface {
+	GetAll(name string, filters Filters) ([]*Collection, Metadata, error)
+	Insert(collection *Collection) error
+	Get(id int64) (*Collection, error)
+	Update(collection *Collection) error
+	Delete(id int64) error
+	GetEntries(collectionID int64) ([]*CollectionEntry, error)
+	PutMovie(collectionID, movieID int64, part int32) error
+	RemoveMovie(collectionID, movieID int64) error
+	GetForMovies(movieIDs []int64) (map[int64]*MovieCollection, error)
+}
+
+type CollectionModel struct {
+	DB *sql.DB
+}
+
+func ValidateCollection(v *validator.Validator, collection *Collection) {
+	v.Check(collection.Name != "", "name", "must be provided")
+	v.Check(len(collection.Name) <= 500, "name", "must not be more than 500 bytes long")
+
+	v.Check(len(collection.Overview) <= 10_000, "overview", "must not be more than 10000 bytes long")
+}
+
+func ValidateCollectionPart(v *validator.Validator, part int32) {
+	v.Check(part > 0, "part", "must be greater than 0")
+	v.Check(part <= 1000, "part", "must not be more than 1000")
+}
+
+func (m CollectionModel) GetAll(name string, filters Filters) ([]*Collection, Metadata, error) {
+	query := fmt.Sprintf(`SELECT count(*) OVER(), id, created_at, name, overview, version
+FROM collections
+WHERE (to_tsvector('simple', name) @@ plainto_tsquery('simple', $1) OR $1 = '')
+ORDER BY %s %s, id ASC
+LIMIT $2 OFFSET $3`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, name, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	defer rows.Close()
+
+	totalRecords := 0
+	collections := []*Collection{}
+
+	for rows.Next() {
+		var collection Collection
+
+		err := rows.Scan(&totalRecords, &collection.ID, &collection.CreatedAt, &collection.Name, &collection.Overview, &collection.Version)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		collections = append(collections, &collection)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return collections, metadata, nil
+}
+
+func (m CollectionModel) Insert(collection *Collection) error {
+	query := `
+INSERT INTO collections (name, overview)
+VALUES ($1, $2)
+RETURNING id, created_at, version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, collection.Name, collection.Overview).Scan(&collection.ID, &collection.CreatedAt, &collection.Version)
+}
+
+func (m CollectionModel) Get(id int64) (*Collection, error) {
+	query := `SELECT id, created_at, name, overview, version
+FROM collections
+WHERE id = $1`
+
+	var collection Collection
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(&collection.ID, &collection.CreatedAt, &collection.Name, &collection.Overview, &collection.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &collection, nil
+}
+
+func (m CollectionModel) Update(collection *Collection) error {
+	query := `UPDATE collections
+SET name = $1, overview = $2, version = version + 1
+WHERE id = $3 AND version = $4
+RETURNING version`
+
+	args := []interface{}{collection.Name, collection.Overview, collection.ID, collection.Version}
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&collection.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Delete removes a collection. Its movies are kept and simply no longer belong
+// to a collection.
+func (m CollectionModel) Delete(id int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `DELETE FROM collections WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// GetEntries lists the movies in a collection in part order. Movies in the
+// trash are left out.
+func (m CollectionModel) GetEntries(collectionID int64) ([]*CollectionEntry, error) {
+	query := `
+SELECT collection_movies.part, movies.id, movies.title, movies.year, movies.runtime, movies.genres, movies.version
+FROM collection_movies
+INNER JOIN movies ON movies.id = collection_movies.movie_id
+WHERE collection_movies.collection_id = $1 AND movies.deleted_at IS NULL
+ORDER BY collection_movies.part ASC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, collectionID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	entries := []*CollectionEntry{}
+
+	for rows.Next() {
+		entry := CollectionEntry{Movie: &Movie{}}
+
+		err := rows.Scan(&entry.Part, &entry.Movie.ID, &entry.Movie.Title, &entry.Movie.Year, &entry.Movie.Runtime, pq.Array(&entry.Movie.Genres), &entry.Movie.Version)
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, &entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// PutMovie makes the movie the given part of the collection, moving it out of
+// any collection it was in before. A part already taken by another movie
+// fails with ErrDuplicateCollectionPart, and a missing collection or movie
+// with ErrRecordNotFound. Movies in the trash keep their place in case they
+// are restored, but give it up to a live movie assigned the same part.
+func (m CollectionModel) PutMovie(collectionID, movieID int64, part int32) error {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+DELETE FROM collection_movies
+USING movies
+WHERE movies.id = collection_movies.movie_id AND movies.deleted_at IS NOT NULL
+AND collection_movies.collection_id = $1 AND collection_movies.part = $2`
+
+	_, err = tx.ExecContext(ctx, query, collectionID, part)
+	if err != nil {
+		return err
+	}
+
+	query = `
+INSERT INTO collection_movies (movie_id, collection_id, part)
+SELECT id, $2, $3
+FROM movies
+WHERE id = $1 AND deleted_at IS NULL
+ON CONFLICT (movie_id) DO UPDATE SET collection_id = EXCLUDED.collection_id, part = EXCLUDED.part`
+
+	result, err := tx.ExecContext(ctx, query, movieID, collectionID, part)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "collection_movies_collection_part_key"`:
+			return ErrDuplicateCollectionPart
+		case err.Error() == `pq: insert or update on table "collection_movies" violates foreign key constraint "collection_movies_collection_id_fkey"`:
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return tx.Commit()
+}
+
+func (m CollectionModel) RemoveMovie(collectionID, movieID int64) error {
+	query := `DELETE FROM collection_movies WHERE collection_id = $1 AND movie_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, collectionID, movieID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// GetForMovies fetches the collections several movies belong to in one query,
+// keyed by movie ID. Movies outside any collection are left out of the map.
+func (m CollectionModel) GetForMovies(movieIDs []int64) (map[int64]*MovieCollection, error) {
+	query := `
+SELECT collection_movies.movie_id, collections.id, collections.name, collection_movies.part
+FROM collection_movies
+INNER JOIN collections ON collections.id = collection_movies.collection_id
+WHERE collection_movies.movie_id = ANY($1)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(movieIDs))
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	collections := make(map[int64]*MovieCollection)
+
+	for rows.Next() {
+		var movieID int64
+		var collection MovieCollection
+
+		err := rows.Scan(&movieID, &collection.ID, &collection.Name, &collection.Part)
+		if err != nil {
+			return nil, err
+		}
+
+		collections[movieID] = &collection
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return collections, nil
+}
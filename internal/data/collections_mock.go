@@ -0,0 +1,39 @@
+package data
+
+type MockCollectionModel struct{}
+
+func (m MockCollectionModel) GetAll(name string, filters Filters) ([]*Collection, Metadata, error) {
+	return nil, Metadata{}, nil
+}
+
+func (m MockCollectionModel) Insert(collection *Collection) error {
+	return nil
+}
+
+func (m MockCollectionModel) Get(id int64) (*Collection, error) {
+	return nil, nil
+}
+
+func (m MockCollectionModel) Update(collection *Collection) error {
+	return nil
+}
+
+func (m MockCollectionModel) Delete(id int64) error {
+	return nil
+}
+
+func (m MockCollectionModel) GetEntries(collectionID int64) ([]*CollectionEntry, error) {
+	return nil, nil
+}
+
+func (m MockCollectionModel) PutMovie(collectionID, movieID int64, part int32) error {
+	return nil
+}
+
+func (m MockCollectionModel) RemoveMovie(collectionID, movieID int64) error {
+	return nil
+}
+
+func (m MockCollectionModel) GetForMovies(movieIDs []int64) (map[int64]*MovieCollection, error) {
+	return nil, nil
+}
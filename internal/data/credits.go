@@ -0,0 +1,222 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+	"github.com/mrityunjaygr8/greenlight/internal/validator"
+)
+
+const (
+	CreditRoleDirector = "director"
+	CreditRoleWriter   = "writer"
+	CreditRoleProducer = "producer"
+	CreditRoleActor    = "actor"
+	CreditRoleComposer = "composer"
+)
+
+var CreditRoles = []string{CreditRoleDirector, CreditRoleWriter, CreditRoleProducer, CreditRoleActor, CreditRoleComposer}
+
+var ErrDuplicateCredit = errors.New("duplicate credit")
+
+type Credit struct {
+	MovieID      int64  `json:"movie_id"`
+	PersonID     int64  `json:"person_id"`
+	PersonName   string `json:"person_name,omitempty"`
+	MovieTitle   string `json:"movie_title,omitempty"`
+	Role         string `json:"role"`
+	Character    string `json:"character,omitempty"`
+	BillingOrder int32  `json:"billing_order"`
+}
+
+type CreditModelInterface interface {
+	GetForMovie(movieID int64) ([]*Credit, error)
+	GetForMovies(movieIDs []int64) (map[int64][]*Credit, error)
+	GetForPerson(personID int64) ([]*Credit, error)
+	Insert(credit *Credit) error
+	Delete(movieID, personID int64, role string) error
+}
+
+type CreditModel struct {
+	DB *sql.DB
+}
+
+func ValidateCredit(v *validator.Validator, credit *Credit) {
+	v.Check(credit.PersonID > 0, "person_id", "must be provided")
+
+	v.Check(credit.Role != "", "role", "must be provided")
+	v.Check(validator.In(credit.Role, CreditRoles...), "role", "invalid role value")
+
+	v.Check(len(credit.Character) <= 500, "character", "must not be more than 500 bytes long")
+	if credit.Role != CreditRoleActor {
+		v.Check(credit.Character == "", "character", "must only be provided for actors")
+	}
+
+	v.Check(credit.BillingOrder >= 0, "billing_order", "must not be negative")
+}
+
+func (m CreditModel) GetForMovie(movieID int64) ([]*Credit, error) {
+	query := `
+SELECT movie_credits.movie_id, movie_credits.person_id, people.name, movie_credits.role, movie_credits.character, movie_credits.billing_order
+FROM movie_credits
+INNER JOIN people ON people.id = movie_credits.person_id
+WHERE movie_credits.movie_id = $1
+ORDER BY movie_credits.billing_order ASC, people.name ASC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, movieID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	credits := []*Credit{}
+
+	for rows.Next() {
+		var credit Credit
+
+		err := rows.Scan(&credit.MovieID, &credit.PersonID, &credit.PersonName, &credit.Role, &credit.Character, &credit.BillingOrder)
+		if err != nil {
+			return nil, err
+		}
+
+		credits = append(credits, &credit)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return credits, nil
+}
+
+// GetForMovies fetches the credits of several movies in one query, keyed by
+// movie ID. Movies without credits are left out of the map.
+func (m CreditModel) GetForMovies(movieIDs []int64) (map[int64][]*Credit, error) {
+	query := `
+SELECT movie_credits.movie_id, movie_credits.person_id, people.name, movie_credits.role, movie_credits.character, movie_credits.billing_order
+FROM movie_credits
+INNER JOIN people ON people.id = movie_credits.person_id
+WHERE movie_credits.movie_id = ANY($1)
+ORDER BY movie_credits.movie_id ASC, movie_credits.billing_order ASC, people.name ASC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(movieIDs))
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	credits := make(map[int64][]*Credit, len(movieIDs))
+
+	for rows.Next() {
+		var credit Credit
+
+		err := rows.Scan(&credit.MovieID, &credit.PersonID, &credit.PersonName, &credit.Role, &credit.Character, &credit.BillingOrder)
+		if err != nil {
+			return nil, err
+		}
+
+		credits[credit.MovieID] = append(credits[credit.MovieID], &credit)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return credits, nil
+}
+
+func (m CreditModel) GetForPerson(personID int64) ([]*Credit, error) {
+	query := `
+SELECT movie_credits.movie_id, movie_credits.person_id, movies.title, movie_credits.role, movie_credits.character, movie_credits.billing_order
+FROM movie_credits
+INNER JOIN movies ON movies.id = movie_credits.movie_id
+WHERE movie_credits.person_id = $1 AND movies.deleted_at IS NULL
+ORDER BY movies.year DESC, movies.id ASC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, personID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	credits := []*Credit{}
+
+	for rows.Next() {
+		var credit Credit
+
+		err := rows.Scan(&credit.MovieID, &credit.PersonID, &credit.MovieTitle, &credit.Role, &credit.Character, &credit.BillingOrder)
+		if err != nil {
+			return nil, err
+		}
+
+		credits = append(credits, &credit)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return credits, nil
+}
+
+func (m CreditModel) Insert(credit *Credit) error {
+	query := `
+INSERT INTO movie_credits (movie_id, person_id, role, character, billing_order)
+VALUES ($1, $2, $3, $4, $5)`
+
+	args := []interface{}{credit.MovieID, credit.PersonID, credit.Role, credit.Character, credit.BillingOrder}
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "movie_credits_pkey"`:
+			return ErrDuplicateCredit
+		case err.Error() == `pq: insert or update on table "movie_credits" violates foreign key constraint "movie_credits_person_id_fkey"`:
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m CreditModel) Delete(movieID, personID int64, role string) error {
+	query := `DELETE FROM movie_credits WHERE movie_id = $1 AND person_id = $2 AND role = $3`
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, movieID, personID, role)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,23 @@
+package data
+
+type MockCreditModel struct{}
+
+func (m MockCreditModel) GetForMovie(movieID int64) ([]*Credit, error) {
+	return nil, nil
+}
+
+func (m MockCreditModel) GetForMovies(movieIDs []int64) (map[int64][]*Credit, error) {
+	return nil, nil
+}
+
+func (m MockCreditModel) GetForPerson(personID int64) ([]*Credit, error) {
+	return nil, nil
+}
+
+func (m MockCreditModel) Insert(credit *Credit) error {
+	return nil
+}
+
+func (m MockCreditModel) Delete(movieID, personID int64, role string) error {
+	return nil
+}
@@ -0,0 +1,217 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+	"github.com/mrityunjaygr8/greenlight/internal/validator"
+)
+
+// DuplicateCriteria controls which pairs of movies FindDuplicates reports.
+// Titles are compared after lowercasing them and dropping everything but
+// letters and digits, so "Se7en" and "SE7EN." normalize to the same title.
+type DuplicateCriteria struct {
+	MovieID          int64
+	MinSimilarity    float64
+	RuntimeTolerance int32
+}
+
+// DuplicateCandidate is a pair of movies which look like the same film. Movie
+// is always the older of the two.
+type DuplicateCandidate struct {
+	Movie      *Movie  `json:"movie"`
+	Duplicate  *Movie  `json:"duplicate"`
+	Similarity float64 `json:"similarity"`
+}
+
+func ValidateDuplicateCriteria(v *validator.Validator, c DuplicateCriteria) {
+	v.Check(c.MinSimilarity > 0, "min_similarity", "must be greater than 0")
+	v.Check(c.MinSimilarity <= 1, "min_similarity", "must not be greater than 1")
+	v.Check(c.RuntimeTolerance >= 0, "runtime_tolerance", "must not be negative")
+	v.Check(c.RuntimeTolerance <= 60, "runtime_tolerance", "must not be more than 60")
+}
+
+// FindDuplicates lists pairs of movies released in the same year whose
+// normalized titles are at least MinSimilarity alike and whose runtimes are
+// within RuntimeTolerance minutes of each other, most similar first. Pairs
+// which hold different IDs from the same external source are known to be
+// distinct films and are left out.
+//
+// The % operator lets each movie's look-alikes be found through the trigram
+// index on the normalized title rather than by comparing every pair, with the
+// threshold it uses set for this transaction only.
+func (m MovieModel) FindDuplicates(criteria DuplicateCriteria, filters Filters) ([]*DuplicateCandidate, Metadata, error) {
+	query := `
+SELECT count(*) OVER(), a.id, a.title, a.year, a.runtime, a.version, b.id, b.title, b.year, b.runtime, b.version,
+	similarity(regexp_replace(lower(a.title), '[^[:alnum:]]+', '', 'g'), regexp_replace(lower(b.title), '[^[:alnum:]]+', '', 'g')) AS score
+FROM movies a
+INNER JOIN movies b
+	ON regexp_replace(lower(b.title), '[^[:alnum:]]+', '', 'g') % regexp_replace(lower(a.title), '[^[:alnum:]]+', '', 'g')
+	AND b.year = a.year AND b.id > a.id
+WHERE a.deleted_at IS NULL AND b.deleted_at IS NULL
+AND abs(a.runtime - b.runtime) <= $1
+AND ($2::bigint = 0 OR a.id = $2 OR b.id = $2)
+AND NOT EXISTS (
+	SELECT 1
+	FROM movie_external_ids ea
+	INNER JOIN movie_external_ids eb ON eb.source = ea.source AND eb.external_id <> ea.external_id
+	WHERE ea.movie_id = a.id AND eb.movie_id = b.id
+)
+ORDER BY score DESC, a.id ASC, b.id ASC
+LIMIT $3 OFFSET $4`
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `SELECT set_config('pg_trgm.similarity_threshold', $1::float8::text, true)`, criteria.MinSimilarity)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	rows, err := tx.QueryContext(ctx, query, criteria.RuntimeTolerance, criteria.MovieID, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	defer rows.Close()
+
+	totalRecords := 0
+	candidates := []*DuplicateCandidate{}
+
+	for rows.Next() {
+		candidate := DuplicateCandidate{Movie: &Movie{}, Duplicate: &Movie{}}
+
+		err := rows.Scan(
+			&totalRecords,
+			&candidate.Movie.ID,
+			&candidate.Movie.Title,
+			&candidate.Movie.Year,
+			&candidate.Movie.Runtime,
+			&candidate.Movie.Version,
+			&candidate.Duplicate.ID,
+			&candidate.Duplicate.Title,
+			&candidate.Duplicate.Year,
+			&candidate.Duplicate.Runtime,
+			&candidate.Duplicate.Version,
+			&candidate.Similarity,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		candidates = append(candidates, &candidate)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return candidates, metadata, nil
+}
+
+// Merge folds the source movie into the target and moves the source to the
+// trash. Reviews, credits, list and watchlist entries, watch history,
+// external IDs, images, translations, releases and collection membership all
+// move across, except where the target already has its own: a user's review
+// of the target wins over their review of the source, for example. Whatever
+// could not move stays with the source, so nothing is lost until the source
+// is purged. The target moves to a new version, recorded as a revision by
+// userID, since what it is shown with has changed.
+func (m MovieModel) Merge(sourceID, targetID, userID int64) (*Movie, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Both movies are locked in ID order, so two merges of the same pair in
+	// opposite directions cannot deadlock.
+	query := `
+SELECT count(*) FROM (
+	SELECT id FROM movies WHERE id = ANY($1) AND deleted_at IS NULL ORDER BY id FOR UPDATE
+) AS locked`
+
+	var locked int
+
+	err = tx.QueryRowContext(ctx, query, pq.Array([]int64{sourceID, targetID})).Scan(&locked)
+	if err != nil {
+		return nil, err
+	}
+
+	if locked != 2 {
+		return nil, ErrRecordNotFound
+	}
+
+	queries := []string{
+		`UPDATE reviews SET movie_id = $2 WHERE movie_id = $1
+AND NOT EXISTS (SELECT 1 FROM reviews t WHERE t.movie_id = $2 AND t.user_id = reviews.user_id)`,
+
+		`UPDATE movie_credits SET movie_id = $2 WHERE movie_id = $1
+AND NOT EXISTS (SELECT 1 FROM movie_credits t WHERE t.movie_id = $2 AND t.person_id = movie_credits.person_id AND t.role = movie_credits.role)`,
+
+		`UPDATE list_entries SET movie_id = $2 WHERE movie_id = $1
+AND NOT EXISTS (SELECT 1 FROM list_entries t WHERE t.movie_id = $2 AND t.list_id = list_entries.list_id)`,
+
+		`UPDATE watchlist_entries SET movie_id = $2 WHERE movie_id = $1
+AND NOT EXISTS (SELECT 1 FROM watchlist_entries t WHERE t.movie_id = $2 AND t.user_id = watchlist_entries.user_id)`,
+
+		`UPDATE watch_history SET movie_id = $2 WHERE movie_id = $1`,
+
+		`UPDATE movie_external_ids SET movie_id = $2 WHERE movie_id = $1
+AND NOT EXISTS (SELECT 1 FROM movie_external_ids t WHERE t.movie_id = $2 AND t.source = movie_external_ids.source)`,
+
+		`UPDATE movie_images SET movie_id = $2 WHERE movie_id = $1
+AND NOT EXISTS (SELECT 1 FROM movie_images t WHERE t.movie_id = $2 AND t.kind = movie_images.kind)`,
+
+		`UPDATE movie_translations SET movie_id = $2 WHERE movie_id = $1
+AND NOT EXISTS (SELECT 1 FROM movie_translations t WHERE t.movie_id = $2 AND t.language = movie_translations.language)`,
+
+		`UPDATE movie_releases SET movie_id = $2 WHERE movie_id = $1
+AND NOT EXISTS (SELECT 1 FROM movie_releases t WHERE t.movie_id = $2 AND t.country = movie_releases.country)`,
+
+		`UPDATE collection_movies SET movie_id = $2 WHERE movie_id = $1
+AND NOT EXISTS (SELECT 1 FROM collection_movies t WHERE t.movie_id = $2)`,
+
+		`UPDATE movies SET deleted_at = NOW() WHERE id = $1`,
+
+		`UPDATE movies SET version = version + 1 WHERE id = $2`,
+	}
+
+	for _, query := range queries {
+		_, err = tx.ExecContext(ctx, query, sourceID, targetID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, id := range []int64{sourceID, targetID} {
+		err = refreshMovieRating(ctx, tx, id)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = recordMovieRevisions(ctx, tx, []int64{targetID}, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return m.Get(targetID)
+}
@@ -0,0 +1,150 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// ExportBatchSize is how many rows are fetched from an export cursor at a
+// time, and so how many movies are handed to the caller at once.
+const ExportBatchSize = 500
+
+// exportChunkSize is how many rows an export without a snapshot reads in one
+// transaction before reopening its cursor.
+const exportChunkSize = 50_000
+
+// Export streams every movie matching movieFilters, in the order of
+// filters.Sort, passing them to fn a batch at a time. Rows are read through a
+// server-side cursor, so memory use does not grow with the size of the
+// catalog. The paging fields of filters are ignored.
+//
+// With snapshot, the whole export runs in a single repeatable read
+// transaction and reflects the catalog at one instant. Otherwise the cursor is
+// reopened in a fresh transaction every exportChunkSize rows, resuming after
+// the last row sent, so a long export never pins one snapshot; a movie edited
+// meanwhile may then appear in its later state, and one whose sort value
+// changes may be missed or sent twice.
+func (m MovieModel) Export(ctx context.Context, movieFilters MovieFilters, filters Filters, fields []string, snapshot bool, fn func(movies []*Movie) error) error {
+	opts := &sql.TxOptions{ReadOnly: true}
+	chunkSize := exportChunkSize
+
+	if snapshot {
+		opts.Isolation = sql.LevelRepeatableRead
+		chunkSize = 0
+	}
+
+	var last *Movie
+
+	for {
+		sent, err := m.exportChunk(ctx, opts, movieFilters, filters, fields, last, chunkSize, fn)
+		if err != nil {
+			return err
+		}
+
+		if sent == nil {
+			return nil
+		}
+
+		last = sent
+	}
+}
+
+// exportChunk reads up to limit rows following after, or every remaining row
+// when limit is zero. It returns the last movie sent, or nil once the export
+// is complete.
+func (m MovieModel) exportChunk(ctx context.Context, opts *sql.TxOptions, movieFilters MovieFilters, filters Filters, fields []string, after *Movie, limit int, fn func(movies []*Movie) error) (*Movie, error) {
+	column := filters.sortColumn()
+	direction := filters.sortDirection()
+
+	where := movieFilters.where()
+
+	if after != nil {
+		operator := ">"
+		if direction == "DESC" {
+			operator = "<"
+		}
+
+		where.and(fmt.Sprintf("(%s, id) %s (?, ?)", column, operator), movieSortValue(after, column), after.ID)
+	}
+
+	columns, scan := selectMovieColumns(fields, column)
+
+	query := fmt.Sprintf(`SELECT %s
+  FROM movies
+  %s
+  ORDER BY %s %s, id %s`, columns, where.String(), column, direction, direction)
+
+	if limit > 0 {
+		query += " LIMIT " + where.param(limit)
+	}
+
+	tx, err := m.DB.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, "DECLARE movie_export NO SCROLL CURSOR FOR "+query, where.args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var last *Movie
+	count := 0
+
+	for {
+		movies, err := fetchExportBatch(ctx, tx, scan)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(movies) == 0 {
+			break
+		}
+
+		err = fn(movies)
+		if err != nil {
+			return nil, err
+		}
+
+		last = movies[len(movies)-1]
+		count += len(movies)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	// A chunk which came up short was the last one.
+	if limit == 0 || count < limit {
+		return nil, nil
+	}
+
+	return last, nil
+}
+
+func fetchExportBatch(ctx context.Context, tx *sql.Tx, scan func(movie *Movie) []interface{}) ([]*Movie, error) {
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf("FETCH FORWARD %d FROM movie_export", ExportBatchSize))
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	movies := []*Movie{}
+
+	for rows.Next() {
+		var movie Movie
+
+		err := rows.Scan(scan(&movie)...)
+		if err != nil {
+			return nil, err
+		}
+
+		movies = append(movies, &movie)
+	}
+
+	return movies, rows.Err()
+}
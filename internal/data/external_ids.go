@@ -0,0 +1,166 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/mrityunjaygr8/greenlight/internal/validator"
+)
+
+var ErrDuplicateExternalID = errors.New("duplicate external id")
+
+type ExternalIDModelInterface interface {
+	GetForMovies(movieIDs []int64) (map[int64]map[string]string, error)
+	Set(movieID int64, externalIDs map[string]string) error
+	Lookup(source, externalID string) (int64, error)
+}
+
+type ExternalIDModel struct {
+	DB *sql.DB
+}
+
+// ValidateExternalIDs checks a movie's external IDs, which map each source,
+// such as "imdb", to the movie's ID there.
+func ValidateExternalIDs(v *validator.Validator, externalIDs map[string]string) {
+	v.Check(len(externalIDs) <= 20, "external_ids", "must not contain more than 20 sources")
+
+	for source, externalID := range externalIDs {
+		if !validator.Matches(source, ExternalSourceRX) || len(source) > 50 {
+			v.AddError("external_ids", "sources must be at most 50 lowercase letters, digits, hyphens and underscores")
+		}
+
+		if strings.TrimSpace(externalID) == "" || len(externalID) > 200 {
+			v.AddError("external_ids", "ids must be provided and not more than 200 bytes long")
+		}
+	}
+}
+
+// GetForMovies fetches the external IDs of several movies in one query, keyed
+// by movie ID. Movies without external IDs are left out of the map.
+func (m ExternalIDModel) GetForMovies(movieIDs []int64) (map[int64]map[string]string, error) {
+	query := `
+SELECT movie_id, source, external_id
+FROM movie_external_ids
+WHERE movie_id = ANY($1)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(movieIDs))
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	externalIDs := make(map[int64]map[string]string)
+
+	for rows.Next() {
+		var movieID int64
+		var source, externalID string
+
+		err := rows.Scan(&movieID, &source, &externalID)
+		if err != nil {
+			return nil, err
+		}
+
+		if externalIDs[movieID] == nil {
+			externalIDs[movieID] = make(map[string]string)
+		}
+
+		externalIDs[movieID][source] = externalID
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return externalIDs, nil
+}
+
+// Set replaces all of a movie's external IDs. Each source can give a movie
+// only one ID, and each ID can belong to only one movie; taking an ID which
+// another movie holds fails with ErrDuplicateExternalID.
+func (m ExternalIDModel) Set(movieID int64, externalIDs map[string]string) error {
+	sources := []string{}
+	ids := []string{}
+
+	for source, externalID := range externalIDs {
+		sources = append(sources, source)
+		ids = append(ids, externalID)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var id int64
+
+	err = tx.QueryRowContext(ctx, `SELECT id FROM movies WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, movieID).Scan(&id)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM movie_external_ids WHERE movie_id = $1 AND NOT (source = ANY($2))`, movieID, pq.Array(sources))
+	if err != nil {
+		return err
+	}
+
+	query := `
+INSERT INTO movie_external_ids (source, external_id, movie_id)
+SELECT v.source, v.external_id, $1
+FROM unnest($2::text[], $3::text[]) AS v(source, external_id)
+ON CONFLICT (movie_id, source) DO UPDATE SET external_id = EXCLUDED.external_id`
+
+	_, err = tx.ExecContext(ctx, query, movieID, pq.Array(sources), pq.Array(ids))
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "movie_external_ids_pkey"`:
+			return ErrDuplicateExternalID
+		default:
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Lookup returns the ID of the movie holding externalID at source. Movies in
+// the trash are not found.
+func (m ExternalIDModel) Lookup(source, externalID string) (int64, error) {
+	query := `
+SELECT movies.id
+FROM movie_external_ids
+INNER JOIN movies ON movies.id = movie_external_ids.movie_id
+WHERE movie_external_ids.source = $1 AND movie_external_ids.external_id = $2 AND movies.deleted_at IS NULL`
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	var movieID int64
+
+	err := m.DB.QueryRowContext(ctx, query, source, externalID).Scan(&movieID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return 0, ErrRecordNotFound
+		default:
+			return 0, err
+		}
+	}
+
+	return movieID, nil
+}
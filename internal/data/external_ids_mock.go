@@ -0,0 +1,15 @@
+package data
+
+type MockExternalIDModel struct{}
+
+func (m MockExternalIDModel) GetForMovies(movieIDs []int64) (map[int64]map[string]string, error) {
+	return nil, nil
+}
+
+func (m MockExternalIDModel) Set(movieID int64, externalIDs map[string]string) error {
+	return nil
+}
+
+func (m MockExternalIDModel) Lookup(source, externalID string) (int64, error) {
+	return 0, ErrRecordNotFound
+}
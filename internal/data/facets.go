@@ -0,0 +1,153 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+
+	"github.com/mrityunjaygr8/greenlight/internal/validator"
+)
+
+const (
+	FacetGenres        = "genres"
+	FacetDecade        = "decade"
+	FacetRuntimeBucket = "runtime_bucket"
+)
+
+var FacetSafelist = []string{FacetGenres, FacetDecade, FacetRuntimeBucket}
+
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// facetQueries hold, for each facet, the SELECT and FROM parts of a grouped
+// count; the WHERE clause comes from the listing's MovieFilters so the counts
+// always describe the same set of movies as the results.
+var facetQueries = map[string]struct {
+	value string
+	from  string
+	order string
+}{
+	FacetGenres: {
+		value: "facet.value",
+		from:  "movies CROSS JOIN unnest(movies.genres) AS facet(value)",
+		order: "count(*) DESC, 1 ASC",
+	},
+	FacetDecade: {
+		value: "((year / 10) * 10)::text || 's'",
+		from:  "movies",
+		order: "1 ASC",
+	},
+	FacetRuntimeBucket: {
+		value: `CASE
+    WHEN runtime < 90 THEN '0-89'
+    WHEN runtime < 120 THEN '90-119'
+    WHEN runtime < 150 THEN '120-149'
+    ELSE '150+'
+  END`,
+		from:  "movies",
+		order: "min(runtime) ASC",
+	},
+}
+
+func ValidateFacets(v *validator.Validator, facets []string) {
+	for _, facet := range facets {
+		v.Check(validator.In(facet, FacetSafelist...), "facets", "invalid facet value")
+	}
+
+	v.Check(validator.Unique(facets), "facets", "must not contain duplicate values")
+}
+
+// Facets counts the movies matching movieFilters grouped by each requested
+// facet. The facet queries run concurrently and share one dbTimeout.
+func (m MovieModel) Facets(movieFilters MovieFilters, facets []string) (map[string][]FacetCount, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+		results  = make(map[string][]FacetCount, len(facets))
+	)
+
+	for _, facet := range facets {
+		wg.Add(1)
+
+		go func(facet string) {
+			defer wg.Done()
+
+			counts, err := facetCounts(ctx, m.DB, movieFilters, facet)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+					cancel()
+				}
+				return
+			}
+
+			results[facet] = counts
+		}(facet)
+	}
+
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	return results, nil
+}
+
+// queryer is what facetCounts needs of a connection: the pool when queries
+// may run concurrently, a transaction when they must share a snapshot.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+func facetCounts(ctx context.Context, q queryer, movieFilters MovieFilters, facet string) ([]FacetCount, error) {
+	fq, ok := facetQueries[facet]
+	if !ok {
+		panic("unsafe facet parameter: " + facet)
+	}
+
+	where := movieFilters.where()
+
+	query := fmt.Sprintf(`SELECT %s, count(*)
+  FROM %s
+  %s
+  GROUP BY 1
+  ORDER BY %s`, fq.value, fq.from, where, fq.order)
+
+	rows, err := q.QueryContext(ctx, query, where.args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	counts := []FacetCount{}
+
+	for rows.Next() {
+		var count FacetCount
+
+		err := rows.Scan(&count.Value, &count.Count)
+		if err != nil {
+			return nil, err
+		}
+
+		counts = append(counts, count)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
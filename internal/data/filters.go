@@ -11,6 +11,9 @@ type Filters struct {
 	PageSize     int
 	Sort         string
 	SortSafelist []string
+	Cursor       string
+	CursorSecret []byte
+	IncludeTotal bool
 }
 
 func ValidateFilters(v *validator.Validator, f Filters) {
@@ -20,6 +23,13 @@ func ValidateFilters(v *validator.Validator, f Filters) {
 	v.Check(f.PageSize <= 100, "page_size", "must be a maximum of 100")
 
 	v.Check(validator.In(f.Sort, f.SortSafelist...), "sort", "invalid sort value")
+
+	if f.Cursor != "" {
+		v.Check(f.Page == 1, "page", "must not be used together with cursor")
+
+		_, err := f.cursor()
+		v.Check(err == nil, "cursor", "invalid cursor or cursor does not match sort")
+	}
 }
 
 func (f Filters) sortColumn() string {
@@ -47,3 +57,23 @@ func (f Filters) limit() int {
 func (f Filters) offset() int {
 	return (f.Page - 1) * f.PageSize
 }
+
+// cursor decodes the client-supplied cursor, returning nil when the listing is
+// paginated by page number instead. A cursor is only valid for the sort it was
+// issued for.
+func (f Filters) cursor() (*cursor, error) {
+	if f.Cursor == "" {
+		return nil, nil
+	}
+
+	c, err := decodeCursor(f.CursorSecret, f.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.Sort != f.Sort {
+		return nil, ErrInvalidCursor
+	}
+
+	return &c, nil
+}
@@ -0,0 +1,495 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/mrityunjaygr8/greenlight/internal/validator"
+)
+
+var (
+	ErrUnknownGenre  = errors.New("unknown genre")
+	ErrGenreConflict = errors.New("genre slug or alias already in use")
+	ErrGenreInUse    = errors.New("genre is still assigned to movies")
+)
+
+var (
+	GenreSlugRX      = regexp.MustCompile("^[a-z0-9]+(-[a-z0-9]+)*$")
+	genreSlugSplitRX = regexp.MustCompile("[^a-z0-9]+")
+)
+
+type Genre struct {
+	ID         int64     `json:"id"`
+	CreatedAt  time.Time `json:"-"`
+	Slug       string    `json:"slug"`
+	Name       string    `json:"name"`
+	Aliases    []string  `json:"aliases"`
+	MovieCount int64     `json:"movie_count"`
+	Version    int32     `json:"version"`
+}
+
+type GenreModelInterface interface {
+	GetAll() ([]*Genre, error)
+	Get(id int64) (*Genre, error)
+	Insert(genre *Genre) error
+	Update(genre *Genre) error
+	Delete(id int64) error
+	Merge(sourceID, targetID int64) (*Genre, error)
+	Resolve(names []string) (slugs []string, unknown []string, err error)
+}
+
+type GenreModel struct {
+	DB *sql.DB
+}
+
+// GenreSlug turns a free-text genre name into its canonical slug form, so
+// that "Sci-Fi", "sci fi" and "SCI_FI" all become "sci-fi". It mirrors the
+// expression used by the migration which normalized the original arrays.
+func GenreSlug(name string) string {
+	return strings.Trim(genreSlugSplitRX.ReplaceAllString(strings.ToLower(name), "-"), "-")
+}
+
+func ValidateGenre(v *validator.Validator, genre *Genre) {
+	v.Check(genre.Name != "", "name", "must be provided")
+	v.Check(len(genre.Name) <= 100, "name", "must not be more than 100 bytes long")
+
+	v.Check(genre.Slug != "", "slug", "must be provided")
+	v.Check(len(genre.Slug) <= 100, "slug", "must not be more than 100 bytes long")
+	v.Check(validator.Matches(genre.Slug, GenreSlugRX), "slug", "must only contain lowercase letters, digits and single hyphens")
+
+	v.Check(len(genre.Aliases) <= 20, "aliases", "must not contain more than 20 aliases")
+	v.Check(validator.Unique(genre.Aliases), "aliases", "must not contain duplicate values")
+	for _, alias := range genre.Aliases {
+		v.Check(validator.Matches(alias, GenreSlugRX), "aliases", "must only contain valid slugs")
+		v.Check(alias != genre.Slug, "aliases", "must not contain the genre's own slug")
+	}
+}
+
+// syncMovieGenreArrays rebuilds the denormalized movies.genres column from
+// movie_genres for the given movies, which keeps the existing array filter and
+// GIN index in GetAll working against canonical slugs. Each movie gets a new
+// revision attributed to the system.
+func syncMovieGenreArrays(ctx context.Context, tx *sql.Tx, movieIDs []int64) error {
+	query := `
+UPDATE movies
+SET genres = (
+  SELECT array_agg(genres.slug ORDER BY movie_genres.position, genres.slug)
+  FROM movie_genres
+  INNER JOIN genres ON genres.id = movie_genres.genre_id
+  WHERE movie_genres.movie_id = movies.id
+), version = version + 1
+WHERE id = ANY($1)`
+
+	_, err := tx.ExecContext(ctx, query, pq.Array(movieIDs))
+	if err != nil {
+		return err
+	}
+
+	return recordMovieRevisions(ctx, tx, movieIDs, 0)
+}
+
+// replaceMovieGenres points movie_genres at the genres named by the slugs, in
+// order. Every slug must already be canonical.
+func replaceMovieGenres(ctx context.Context, tx *sql.Tx, movieID int64, slugs []string) error {
+	_, err := tx.ExecContext(ctx, `DELETE FROM movie_genres WHERE movie_id = $1`, movieID)
+	if err != nil {
+		return err
+	}
+
+	query := `
+INSERT INTO movie_genres (movie_id, genre_id, position)
+SELECT $1, genres.id, x.ord
+FROM unnest($2::text[]) WITH ORDINALITY AS x(slug, ord)
+INNER JOIN genres ON genres.slug = x.slug`
+
+	result, err := tx.ExecContext(ctx, query, movieID, pq.Array(slugs))
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if int(rowsAffected) != len(slugs) {
+		return ErrUnknownGenre
+	}
+
+	return nil
+}
+
+func genreMovieIDs(ctx context.Context, tx *sql.Tx, genreID int64) ([]int64, error) {
+	var movieIDs []int64
+
+	err := tx.QueryRowContext(ctx, `SELECT COALESCE(array_agg(movie_id), '{}') FROM movie_genres WHERE genre_id = $1`, genreID).Scan(pq.Array(&movieIDs))
+	return movieIDs, err
+}
+
+func (m GenreModel) GetAll() ([]*Genre, error) {
+	query := `
+SELECT genres.id, genres.created_at, genres.slug, genres.name, genres.aliases, count(movie_genres.movie_id), genres.version
+FROM genres
+LEFT JOIN movie_genres ON movie_genres.genre_id = genres.id
+GROUP BY genres.id
+ORDER BY genres.name ASC, genres.id ASC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	genres := []*Genre{}
+
+	for rows.Next() {
+		var genre Genre
+
+		err := rows.Scan(&genre.ID, &genre.CreatedAt, &genre.Slug, &genre.Name, pq.Array(&genre.Aliases), &genre.MovieCount, &genre.Version)
+		if err != nil {
+			return nil, err
+		}
+
+		genres = append(genres, &genre)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return genres, nil
+}
+
+func (m GenreModel) Get(id int64) (*Genre, error) {
+	query := `
+SELECT genres.id, genres.created_at, genres.slug, genres.name, genres.aliases,
+  (SELECT count(*) FROM movie_genres WHERE movie_genres.genre_id = genres.id), genres.version
+FROM genres
+WHERE genres.id = $1`
+
+	var genre Genre
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(&genre.ID, &genre.CreatedAt, &genre.Slug, &genre.Name, pq.Array(&genre.Aliases), &genre.MovieCount, &genre.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &genre, nil
+}
+
+// checkGenreConflict makes sure neither the slug nor any alias of the genre is
+// already claimed by a different genre, since Resolve relies on every slug and
+// alias pointing at exactly one genre.
+func checkGenreConflict(ctx context.Context, tx *sql.Tx, genre *Genre) error {
+	query := `
+SELECT EXISTS (
+  SELECT 1 FROM genres
+  WHERE id <> $1
+  AND (slug = ANY($2) OR aliases && $2)
+)`
+
+	names := append([]string{genre.Slug}, genre.Aliases...)
+
+	var exists bool
+
+	err := tx.QueryRowContext(ctx, query, genre.ID, pq.Array(names)).Scan(&exists)
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		return ErrGenreConflict
+	}
+
+	return nil
+}
+
+func (m GenreModel) Insert(genre *Genre) error {
+	if genre.Aliases == nil {
+		genre.Aliases = []string{}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = checkGenreConflict(ctx, tx, genre)
+	if err != nil {
+		return err
+	}
+
+	query := `
+INSERT INTO genres (slug, name, aliases)
+VALUES ($1, $2, $3)
+RETURNING id, created_at, version`
+
+	err = tx.QueryRowContext(ctx, query, genre.Slug, genre.Name, pq.Array(genre.Aliases)).Scan(&genre.ID, &genre.CreatedAt, &genre.Version)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "genres_slug_key"`:
+			return ErrGenreConflict
+		default:
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (m GenreModel) Update(genre *Genre) error {
+	if genre.Aliases == nil {
+		genre.Aliases = []string{}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = checkGenreConflict(ctx, tx, genre)
+	if err != nil {
+		return err
+	}
+
+	var oldSlug string
+
+	err = tx.QueryRowContext(ctx, `SELECT slug FROM genres WHERE id = $1 FOR UPDATE`, genre.ID).Scan(&oldSlug)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	query := `UPDATE genres
+SET slug = $1, name = $2, aliases = $3, version = version + 1
+WHERE id = $4 AND version = $5
+RETURNING version`
+
+	args := []interface{}{genre.Slug, genre.Name, pq.Array(genre.Aliases), genre.ID, genre.Version}
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&genre.Version)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "genres_slug_key"`:
+			return ErrGenreConflict
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	// movies.genres holds slugs only, so the tagged movies are left alone
+	// unless the slug itself changed.
+	if genre.Slug != oldSlug {
+		movieIDs, err := genreMovieIDs(ctx, tx, genre.ID)
+		if err != nil {
+			return err
+		}
+
+		err = syncMovieGenreArrays(ctx, tx, movieIDs)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (m GenreModel) Delete(id int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `DELETE FROM genres WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: update or delete on table "genres" violates foreign key constraint "movie_genres_genre_id_fkey" on table "movie_genres"`:
+			return ErrGenreInUse
+		default:
+			return err
+		}
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// Merge folds the source genre into the target. Movies tagged with the source
+// are retagged with the target, the source slug and aliases become aliases of
+// the target so old names keep resolving, and the source genre is removed.
+func (m GenreModel) Merge(sourceID, targetID int64) (*Genre, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var source Genre
+
+	err = tx.QueryRowContext(ctx, `SELECT id, slug, aliases FROM genres WHERE id = $1 FOR UPDATE`, sourceID).Scan(&source.ID, &source.Slug, pq.Array(&source.Aliases))
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	movieIDs, err := genreMovieIDs(ctx, tx, source.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+UPDATE genres
+SET aliases = ARRAY(SELECT DISTINCT unnest(aliases || $2::text[]) ORDER BY 1), version = version + 1
+WHERE id = $1
+RETURNING version`
+
+	var version int32
+
+	err = tx.QueryRowContext(ctx, query, targetID, pq.Array(append([]string{source.Slug}, source.Aliases...))).Scan(&version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+INSERT INTO movie_genres (movie_id, genre_id, position)
+SELECT movie_id, $2, position FROM movie_genres WHERE genre_id = $1
+ON CONFLICT (movie_id, genre_id) DO NOTHING`, source.ID, targetID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM movie_genres WHERE genre_id = $1`, source.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM genres WHERE id = $1`, source.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = syncMovieGenreArrays(ctx, tx, movieIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return m.Get(targetID)
+}
+
+// Resolve maps free-text genre names onto canonical genre slugs by matching
+// their slug form against each genre's slug and aliases. Names which match no
+// genre are returned in unknown, in the order given.
+func (m GenreModel) Resolve(names []string) ([]string, []string, error) {
+	candidates := make([]string, len(names))
+	for i, name := range names {
+		candidates[i] = GenreSlug(name)
+	}
+
+	query := `SELECT slug, aliases FROM genres WHERE slug = ANY($1) OR aliases && $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(candidates))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	defer rows.Close()
+
+	canonical := make(map[string]string)
+
+	for rows.Next() {
+		var slug string
+		var aliases []string
+
+		err := rows.Scan(&slug, pq.Array(&aliases))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		canonical[slug] = slug
+		for _, alias := range aliases {
+			canonical[alias] = slug
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	slugs := []string{}
+	unknown := []string{}
+
+	for i, candidate := range candidates {
+		slug, ok := canonical[candidate]
+		if !ok {
+			unknown = append(unknown, names[i])
+			continue
+		}
+
+		slugs = append(slugs, slug)
+	}
+
+	return slugs, unknown, nil
+}
@@ -0,0 +1,31 @@
+package data
+
+type MockGenreModel struct{}
+
+func (m MockGenreModel) GetAll() ([]*Genre, error) {
+	return nil, nil
+}
+
+func (m MockGenreModel) Get(id int64) (*Genre, error) {
+	return nil, nil
+}
+
+func (m MockGenreModel) Insert(genre *Genre) error {
+	return nil
+}
+
+func (m MockGenreModel) Update(genre *Genre) error {
+	return nil
+}
+
+func (m MockGenreModel) Delete(id int64) error {
+	return nil
+}
+
+func (m MockGenreModel) Merge(sourceID, targetID int64) (*Genre, error) {
+	return nil, nil
+}
+
+func (m MockGenreModel) Resolve(names []string) ([]string, []string, error) {
+	return names, nil, nil
+}
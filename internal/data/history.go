@@ -0,0 +1,102 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/mrityunjaygr8/greenlight/internal/validator"
+)
+
+type HistoryEntry struct {
+	ID        int64     `json:"id"`
+	WatchedAt time.Time `json:"watched_at"`
+	MovieID   int64     `json:"-"`
+	UserID    int64     `json:"-"`
+	Movie     *Movie    `json:"movie,omitempty"`
+}
+
+type HistoryModelInterface interface {
+	GetAll(userID int64, filters Filters) ([]*HistoryEntry, Metadata, error)
+	Insert(entry *HistoryEntry) error
+}
+
+type HistoryModel struct {
+	DB *sql.DB
+}
+
+func ValidateHistoryEntry(v *validator.Validator, entry *HistoryEntry) {
+	v.Check(entry.MovieID > 0, "movie_id", "must be provided")
+	v.Check(!entry.WatchedAt.After(time.Now()), "watched_at", "must not be in the future")
+}
+
+func (m HistoryModel) GetAll(userID int64, filters Filters) ([]*HistoryEntry, Metadata, error) {
+	query := fmt.Sprintf(`SELECT count(*) OVER(), watch_history.id, watch_history.watched_at, movies.id, movies.title, movies.year, movies.runtime, movies.genres, movies.version
+FROM watch_history
+INNER JOIN movies ON movies.id = watch_history.movie_id
+WHERE watch_history.user_id = $1 AND movies.deleted_at IS NULL
+ORDER BY %s %s, watch_history.id DESC
+LIMIT $2 OFFSET $3`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	defer rows.Close()
+
+	totalRecords := 0
+	entries := []*HistoryEntry{}
+
+	for rows.Next() {
+		entry := HistoryEntry{UserID: userID, Movie: &Movie{}}
+
+		err := rows.Scan(&totalRecords, &entry.ID, &entry.WatchedAt, &entry.Movie.ID, &entry.Movie.Title, &entry.Movie.Year, &entry.Movie.Runtime, pq.Array(&entry.Movie.Genres), &entry.Movie.Version)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		entry.MovieID = entry.Movie.ID
+		entries = append(entries, &entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return entries, metadata, nil
+}
+
+// Insert records the user watching the movie. Movies in the trash cannot be
+// watched.
+func (m HistoryModel) Insert(entry *HistoryEntry) error {
+	query := `
+INSERT INTO watch_history (user_id, movie_id, watched_at)
+SELECT $1, id, $3
+FROM movies
+WHERE id = $2 AND deleted_at IS NULL
+RETURNING id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, entry.UserID, entry.MovieID, entry.WatchedAt).Scan(&entry.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,11 @@
+package data
+
+type MockHistoryModel struct{}
+
+func (m MockHistoryModel) GetAll(userID int64, filters Filters) ([]*HistoryEntry, Metadata, error) {
+	return nil, Metadata{}, nil
+}
+
+func (m MockHistoryModel) Insert(entry *HistoryEntry) error {
+	return nil
+}
@@ -0,0 +1,207 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	MovieImagePoster   = "poster"
+	MovieImageBackdrop = "backdrop"
+)
+
+var MovieImageKinds = []string{MovieImagePoster, MovieImageBackdrop}
+
+// MovieImage is a piece of artwork for a movie. The keys locate the image and
+// its thumbnail in the blob store; the URLs are filled in from them before the
+// image is sent to clients.
+type MovieImage struct {
+	MovieID      int64     `json:"-"`
+	Kind         string    `json:"-"`
+	Key          string    `json:"-"`
+	ThumbnailKey string    `json:"-"`
+	URL          string    `json:"url"`
+	ThumbnailURL string    `json:"thumbnail_url"`
+	ContentType  string    `json:"content_type"`
+	Width        int       `json:"width"`
+	Height       int       `json:"height"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+type ImageModelInterface interface {
+	GetForMovies(movieIDs []int64) (map[int64][]*MovieImage, error)
+	Put(image *MovieImage) (*MovieImage, error)
+	Delete(movieID int64, kind string) (*MovieImage, error)
+	Referenced(keys []string) (map[string]bool, error)
+}
+
+type ImageModel struct {
+	DB *sql.DB
+}
+
+// GetForMovies fetches the images of several movies in one query, keyed by
+// movie ID. Movies without images are left out of the map.
+func (m ImageModel) GetForMovies(movieIDs []int64) (map[int64][]*MovieImage, error) {
+	query := `
+SELECT movie_id, kind, key, thumbnail_key, content_type, width, height, updated_at
+FROM movie_images
+WHERE movie_id = ANY($1)
+ORDER BY movie_id ASC, kind ASC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(movieIDs))
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	images := make(map[int64][]*MovieImage)
+
+	for rows.Next() {
+		var image MovieImage
+
+		err := rows.Scan(&image.MovieID, &image.Kind, &image.Key, &image.ThumbnailKey, &image.ContentType, &image.Width, &image.Height, &image.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		images[image.MovieID] = append(images[image.MovieID], &image)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return images, nil
+}
+
+// Put sets the image of its kind for a movie, returning the image it replaced,
+// if any, so that the caller can remove the old blobs.
+func (m ImageModel) Put(image *MovieImage) (*MovieImage, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	previous, err := lockMovieImage(ctx, tx, image.MovieID, image.Kind)
+	if err != nil && !errors.Is(err, ErrRecordNotFound) {
+		return nil, err
+	}
+
+	query := `
+INSERT INTO movie_images (movie_id, kind, key, thumbnail_key, content_type, width, height)
+SELECT $1, $2, $3, $4, $5, $6, $7
+FROM movies
+WHERE id = $1 AND deleted_at IS NULL
+ON CONFLICT (movie_id, kind) DO UPDATE
+SET key = EXCLUDED.key, thumbnail_key = EXCLUDED.thumbnail_key, content_type = EXCLUDED.content_type,
+    width = EXCLUDED.width, height = EXCLUDED.height, updated_at = NOW()
+RETURNING updated_at`
+
+	args := []interface{}{image.MovieID, image.Kind, image.Key, image.ThumbnailKey, image.ContentType, image.Width, image.Height}
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&image.UpdatedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return previous, tx.Commit()
+}
+
+// Delete removes the image of the given kind from a movie and returns it, so
+// that the caller can remove its blobs.
+func (m ImageModel) Delete(movieID int64, kind string) (*MovieImage, error) {
+	query := `
+DELETE FROM movie_images
+WHERE movie_id = $1 AND kind = $2
+RETURNING movie_id, kind, key, thumbnail_key, content_type, width, height, updated_at`
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	var image MovieImage
+
+	err := m.DB.QueryRowContext(ctx, query, movieID, kind).Scan(&image.MovieID, &image.Kind, &image.Key, &image.ThumbnailKey, &image.ContentType, &image.Width, &image.Height, &image.UpdatedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &image, nil
+}
+
+// Referenced reports which of the given blob keys belong to an image, as
+// either the image itself or its thumbnail.
+func (m ImageModel) Referenced(keys []string) (map[string]bool, error) {
+	query := `
+SELECT key FROM movie_images WHERE key = ANY($1)
+UNION
+SELECT thumbnail_key FROM movie_images WHERE thumbnail_key = ANY($1)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(keys))
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	referenced := make(map[string]bool)
+
+	for rows.Next() {
+		var key string
+
+		err := rows.Scan(&key)
+		if err != nil {
+			return nil, err
+		}
+
+		referenced[key] = true
+	}
+
+	return referenced, rows.Err()
+}
+
+func lockMovieImage(ctx context.Context, tx *sql.Tx, movieID int64, kind string) (*MovieImage, error) {
+	query := `
+SELECT movie_id, kind, key, thumbnail_key, content_type, width, height, updated_at
+FROM movie_images
+WHERE movie_id = $1 AND kind = $2
+FOR UPDATE`
+
+	var image MovieImage
+
+	err := tx.QueryRowContext(ctx, query, movieID, kind).Scan(&image.MovieID, &image.Kind, &image.Key, &image.ThumbnailKey, &image.ContentType, &image.Width, &image.Height, &image.UpdatedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &image, nil
+}
@@ -0,0 +1,19 @@
+package data
+
+type MockImageModel struct{}
+
+func (m MockImageModel) GetForMovies(movieIDs []int64) (map[int64][]*MovieImage, error) {
+	return nil, nil
+}
+
+func (m MockImageModel) Put(image *MovieImage) (*MovieImage, error) {
+	return nil, nil
+}
+
+func (m MockImageModel) Delete(movieID int64, kind string) (*MovieImage, error) {
+	return nil, nil
+}
+
+func (m MockImageModel) Referenced(keys []string) (map[string]bool, error) {
+	return nil, nil
+}
@@ -0,0 +1,264 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/mrityunjaygr8/greenlight/internal/validator"
+)
+
+// ImportBatchSize is how many rows of an import are written per transaction.
+const ImportBatchSize = 500
+
+const (
+	ImportActionInserted = "inserted"
+	ImportActionUpdated  = "updated"
+)
+
+var ExternalSourceRX = regexp.MustCompile("^[a-z0-9]+(?:[-_][a-z0-9]+)*$")
+
+// ImportRow is a single movie read from an import file. Writing it fills in
+// Action, or Error when the row had to be skipped.
+type ImportRow struct {
+	Line       int    `json:"line"`
+	ExternalID string `json:"external_id,omitempty"`
+	Movie      *Movie `json:"movie"`
+	Action     string `json:"-"`
+	Error      string `json:"-"`
+}
+
+// ImportRowError reports why a row of an import file was rejected. Line is the
+// line number in the file, counting a CSV header as line 1.
+type ImportRowError struct {
+	Line       int               `json:"line"`
+	ExternalID string            `json:"external_id,omitempty"`
+	Errors     map[string]string `json:"errors"`
+}
+
+func ValidateExternalSource(v *validator.Validator, source string) {
+	v.Check(source != "", "source", "must be provided")
+	v.Check(len(source) <= 50, "source", "must not be more than 50 bytes long")
+	v.Check(validator.Matches(source, ExternalSourceRX), "source", "must only contain lowercase letters, digits, hyphens and underscores")
+}
+
+func ValidateExternalID(v *validator.Validator, externalID string) {
+	v.Check(len(externalID) <= 200, "external_id", "must not be more than 200 bytes long")
+}
+
+// importBatch writes the rows. With dryRun the caller rolls the writes back
+// afterwards, so new movies are given placeholder IDs rather than values drawn
+// from the sequence, which a rollback would not return.
+func importBatch(ctx context.Context, tx *sql.Tx, rows []*ImportRow, source string, upsert, dryRun bool, userID int64) error {
+	existing, err := matchExternalIDs(ctx, tx, rows, source)
+	if err != nil {
+		return err
+	}
+
+	var inserts, updates []*ImportRow
+
+	for _, row := range rows {
+		match, ok := existing[row.ExternalID]
+
+		switch {
+		case !ok:
+			inserts = append(inserts, row)
+		case match.deleted:
+			row.Error = "external_id belongs to a movie in the trash"
+		case !upsert:
+			row.Error = "external_id already exists"
+		default:
+			row.Movie.ID = match.movieID
+			updates = append(updates, row)
+		}
+	}
+
+	err = importInserts(ctx, tx, inserts, source, dryRun)
+	if err != nil {
+		return err
+	}
+
+	err = importUpdates(ctx, tx, updates)
+	if err != nil {
+		return err
+	}
+
+	movieIDs := make([]int64, 0, len(inserts)+len(updates))
+	for _, row := range append(inserts, updates...) {
+		movieIDs = append(movieIDs, row.Movie.ID)
+	}
+
+	if len(movieIDs) == 0 {
+		return nil
+	}
+
+	// The genres arrays were written already, so movie_genres is rebuilt from
+	// them rather than row by row.
+	_, err = tx.ExecContext(ctx, `DELETE FROM movie_genres WHERE movie_id = ANY($1)`, pq.Array(movieIDs))
+	if err != nil {
+		return err
+	}
+
+	query := `
+INSERT INTO movie_genres (movie_id, genre_id, position)
+SELECT movies.id, genres.id, x.ord
+FROM movies
+CROSS JOIN LATERAL unnest(movies.genres) WITH ORDINALITY AS x(slug, ord)
+INNER JOIN genres ON genres.slug = x.slug
+WHERE movies.id = ANY($1)`
+
+	_, err = tx.ExecContext(ctx, query, pq.Array(movieIDs))
+	if err != nil {
+		return err
+	}
+
+	return recordMovieRevisions(ctx, tx, movieIDs, userID)
+}
+
+type externalIDMatch struct {
+	movieID int64
+	deleted bool
+}
+
+func matchExternalIDs(ctx context.Context, tx *sql.Tx, rows []*ImportRow, source string) (map[string]externalIDMatch, error) {
+	externalIDs := []string{}
+	for _, row := range rows {
+		if row.ExternalID != "" {
+			externalIDs = append(externalIDs, row.ExternalID)
+		}
+	}
+
+	existing := make(map[string]externalIDMatch)
+
+	if len(externalIDs) == 0 {
+		return existing, nil
+	}
+
+	query := `
+SELECT movie_external_ids.external_id, movies.id, movies.deleted_at IS NOT NULL
+FROM movie_external_ids
+INNER JOIN movies ON movies.id = movie_external_ids.movie_id
+WHERE movie_external_ids.source = $1 AND movie_external_ids.external_id = ANY($2)`
+
+	dbRows, err := tx.QueryContext(ctx, query, source, pq.Array(externalIDs))
+	if err != nil {
+		return nil, err
+	}
+
+	defer dbRows.Close()
+
+	for dbRows.Next() {
+		var externalID string
+		var match externalIDMatch
+
+		err := dbRows.Scan(&externalID, &match.movieID, &match.deleted)
+		if err != nil {
+			return nil, err
+		}
+
+		existing[externalID] = match
+	}
+
+	return existing, dbRows.Err()
+}
+
+// movieColumnArrays splits the rows into one array per movie column, for
+// passing to unnest. Genres are comma-joined since Postgres has no jagged
+// arrays; canonical slugs never contain commas.
+func movieColumnArrays(rows []*ImportRow) (titles []string, years, runtimes []int64, genres []string) {
+	for _, row := range rows {
+		titles = append(titles, row.Movie.Title)
+		years = append(years, int64(row.Movie.Year))
+		runtimes = append(runtimes, int64(row.Movie.Runtime))
+		genres = append(genres, strings.Join(row.Movie.Genres, ","))
+	}
+
+	return titles, years, runtimes, genres
+}
+
+func importInserts(ctx context.Context, tx *sql.Tx, rows []*ImportRow, source string, dryRun bool) error {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	// IDs are drawn from the sequence up front, which ties each row to its
+	// movie without relying on the order of RETURNING. Dry runs use negative
+	// IDs instead, which no real movie has, to leave the sequence untouched.
+	movieIDs := make([]int64, len(rows))
+
+	if dryRun {
+		for i := range movieIDs {
+			movieIDs[i] = -int64(i + 1)
+		}
+	} else {
+		err := tx.QueryRowContext(ctx, `SELECT array_agg(nextval(pg_get_serial_sequence('movies', 'id'))) FROM generate_series(1, $1)`, len(rows)).Scan(pq.Array(&movieIDs))
+		if err != nil {
+			return err
+		}
+	}
+
+	for i, row := range rows {
+		row.Movie.ID = movieIDs[i]
+		row.Movie.Version = 1
+		row.Action = ImportActionInserted
+	}
+
+	titles, years, runtimes, genres := movieColumnArrays(rows)
+
+	query := `
+INSERT INTO movies (id, title, year, runtime, genres)
+SELECT v.id, v.title, v.year, v.runtime, string_to_array(v.genres, ',')
+FROM unnest($1::bigint[], $2::text[], $3::integer[], $4::integer[], $5::text[]) AS v(id, title, year, runtime, genres)`
+
+	_, err := tx.ExecContext(ctx, query, pq.Array(movieIDs), pq.Array(titles), pq.Array(years), pq.Array(runtimes), pq.Array(genres))
+	if err != nil {
+		return err
+	}
+
+	var linkedIDs []int64
+	var externalIDs []string
+
+	for _, row := range rows {
+		if row.ExternalID != "" {
+			linkedIDs = append(linkedIDs, row.Movie.ID)
+			externalIDs = append(externalIDs, row.ExternalID)
+		}
+	}
+
+	if len(externalIDs) == 0 {
+		return nil
+	}
+
+	query = `
+INSERT INTO movie_external_ids (source, external_id, movie_id)
+SELECT $1, v.external_id, v.movie_id
+FROM unnest($2::text[], $3::bigint[]) AS v(external_id, movie_id)`
+
+	_, err = tx.ExecContext(ctx, query, source, pq.Array(externalIDs), pq.Array(linkedIDs))
+	return err
+}
+
+func importUpdates(ctx context.Context, tx *sql.Tx, rows []*ImportRow) error {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	movieIDs := make([]int64, len(rows))
+	for i, row := range rows {
+		movieIDs[i] = row.Movie.ID
+		row.Action = ImportActionUpdated
+	}
+
+	titles, years, runtimes, genres := movieColumnArrays(rows)
+
+	query := `
+UPDATE movies
+SET title = v.title, year = v.year, runtime = v.runtime, genres = string_to_array(v.genres, ','), version = movies.version + 1
+FROM unnest($1::bigint[], $2::text[], $3::integer[], $4::integer[], $5::text[]) AS v(id, title, year, runtime, genres)
+WHERE movies.id = v.id`
+
+	_, err := tx.ExecContext(ctx, query, pq.Array(movieIDs), pq.Array(titles), pq.Array(years), pq.Array(runtimes), pq.Array(genres))
+	return err
+}
@@ -0,0 +1,442 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	JobKindMovieImport = "movie_import"
+)
+
+const (
+	JobStatusQueued    = "queued"
+	JobStatusRunning   = "running"
+	JobStatusSucceeded = "succeeded"
+	JobStatusFailed    = "failed"
+	JobStatusCancelled = "cancelled"
+)
+
+const jobBatchTimeout = 30 * time.Second
+
+var (
+	ErrJobNotRunning    = errors.New("job is no longer running")
+	ErrInvalidJobStatus = errors.New("job cannot change to that status")
+	ErrNoJobsQueued     = errors.New("no jobs queued")
+)
+
+// Job is a unit of background work, such as a movie import, which a worker
+// carries out in batches. Progress is saved after every batch, so a job picks
+// up where it left off when resumed.
+type Job struct {
+	ID        int64            `json:"id"`
+	CreatedAt time.Time        `json:"created_at"`
+	UpdatedAt time.Time        `json:"updated_at"`
+	UserID    int64            `json:"user_id"`
+	Kind      string           `json:"kind"`
+	Status    string           `json:"status"`
+	Params    json.RawMessage  `json:"params"`
+	Total     int              `json:"total"`
+	Processed int              `json:"processed"`
+	Inserted  int              `json:"inserted"`
+	Updated   int              `json:"updated"`
+	Failed    int              `json:"failed"`
+	NextItem  int              `json:"-"`
+	Attempt   int              `json:"-"`
+	Errors    []ImportRowError `json:"errors"`
+	Error     string           `json:"error,omitempty"`
+}
+
+// ImportParams are the options of a movie import job.
+type ImportParams struct {
+	Source string `json:"source"`
+	Upsert bool   `json:"upsert"`
+	DryRun bool   `json:"dry_run"`
+}
+
+type JobModelInterface interface {
+	InsertImport(job *Job, rows []*ImportRow) error
+	Get(id int64) (*Job, error)
+	Claim() (*Job, error)
+	NextImportRows(job *Job, limit int) ([]*ImportRow, error)
+	ImportBatch(job *Job, rows []*ImportRow) error
+	Finish(job *Job, status, message string) error
+	Cancel(id int64) error
+	Resume(id int64) error
+	Requeue(id int64) error
+	RequeueInterrupted() (int64, error)
+}
+
+type JobModel struct {
+	DB *sql.DB
+}
+
+// InsertImport queues a movie import job. The rows are stored as the job's
+// items, which workers read back a batch at a time.
+func (m JobModel) InsertImport(job *Job, rows []*ImportRow) error {
+	errs, err := json.Marshal(job.Errors)
+	if err != nil {
+		return err
+	}
+
+	items, err := json.Marshal(rows)
+	if err != nil {
+		return err
+	}
+
+	query := `
+INSERT INTO jobs (user_id, kind, status, params, total, processed, failed, errors)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING id, created_at, updated_at`
+
+	args := []interface{}{job.UserID, JobKindMovieImport, JobStatusQueued, []byte(job.Params), job.Total, job.Processed, job.Failed, errs}
+
+	ctx, cancel := context.WithTimeout(context.Background(), jobBatchTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&job.ID, &job.CreatedAt, &job.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	query = `
+INSERT INTO job_items (job_id, position, payload)
+SELECT $1, item.position - 1, item.payload
+FROM jsonb_array_elements($2::jsonb) WITH ORDINALITY AS item(payload, position)`
+
+	_, err = tx.ExecContext(ctx, query, job.ID, items)
+	if err != nil {
+		return err
+	}
+
+	job.Kind = JobKindMovieImport
+	job.Status = JobStatusQueued
+
+	return tx.Commit()
+}
+
+const jobColumns = `id, created_at, updated_at, user_id, kind, status, params, total, processed, inserted, updated, failed, next_item, attempt, errors, error`
+
+func scanJob(row interface{ Scan(...interface{}) error }) (*Job, error) {
+	var job Job
+	var errs []byte
+
+	err := row.Scan(&job.ID, &job.CreatedAt, &job.UpdatedAt, &job.UserID, &job.Kind, &job.Status, &job.Params, &job.Total, &job.Processed, &job.Inserted, &job.Updated, &job.Failed, &job.NextItem, &job.Attempt, &errs, &job.Error)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(errs, &job.Errors)
+	if err != nil {
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+func (m JobModel) Get(id int64) (*Job, error) {
+	query := `SELECT ` + jobColumns + ` FROM jobs WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	job, err := scanJob(m.DB.QueryRowContext(ctx, query, id))
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return job, nil
+}
+
+// Claim marks the oldest queued job as running and returns it. SKIP LOCKED
+// lets several workers claim jobs at once without handing out the same one
+// twice. Each claim starts a new attempt, so a worker still holding an earlier
+// claim, such as one from before the job was cancelled and resumed, can no
+// longer write to it. It returns ErrNoJobsQueued when there is nothing to do.
+func (m JobModel) Claim() (*Job, error) {
+	query := `
+UPDATE jobs SET status = $1, attempt = attempt + 1, updated_at = NOW()
+WHERE id = (
+  SELECT id FROM jobs
+  WHERE status = $2
+  ORDER BY id ASC
+  LIMIT 1
+  FOR UPDATE SKIP LOCKED
+)
+RETURNING ` + jobColumns
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	job, err := scanJob(m.DB.QueryRowContext(ctx, query, JobStatusRunning, JobStatusQueued))
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNoJobsQueued
+		default:
+			return nil, err
+		}
+	}
+
+	return job, nil
+}
+
+// NextImportRows reads the next batch of rows the job has yet to write. An
+// empty result means the job has been fully processed.
+func (m JobModel) NextImportRows(job *Job, limit int) ([]*ImportRow, error) {
+	query := `
+SELECT payload FROM job_items
+WHERE job_id = $1 AND position >= $2
+ORDER BY position ASC
+LIMIT $3`
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	dbRows, err := m.DB.QueryContext(ctx, query, job.ID, job.NextItem, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	defer dbRows.Close()
+
+	rows := []*ImportRow{}
+
+	for dbRows.Next() {
+		var payload []byte
+
+		err := dbRows.Scan(&payload)
+		if err != nil {
+			return nil, err
+		}
+
+		var row ImportRow
+
+		err = json.Unmarshal(payload, &row)
+		if err != nil {
+			return nil, err
+		}
+
+		rows = append(rows, &row)
+	}
+
+	if err = dbRows.Err(); err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+// ImportBatch writes one batch of an import job and saves the job's progress
+// in the same transaction, so each batch is applied exactly once however the
+// job is interrupted. It returns ErrJobNotRunning if the job was cancelled in
+// the meantime, or if the worker's claim is stale: the job has since been
+// claimed again, or its progress has moved past the rows read. Dry runs write
+// the batch inside a savepoint which is then rolled back, keeping only the
+// progress, and do not use up movie IDs.
+func (m JobModel) ImportBatch(job *Job, rows []*ImportRow) error {
+	var params ImportParams
+
+	err := json.Unmarshal(job.Params, &params)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), jobBatchTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var status string
+	var attempt, nextItem int
+
+	err = tx.QueryRowContext(ctx, `SELECT status, attempt, next_item FROM jobs WHERE id = $1 FOR UPDATE`, job.ID).Scan(&status, &attempt, &nextItem)
+	if err != nil {
+		return err
+	}
+
+	if status != JobStatusRunning || attempt != job.Attempt || nextItem != job.NextItem {
+		return ErrJobNotRunning
+	}
+
+	_, err = tx.ExecContext(ctx, `SAVEPOINT import_batch`)
+	if err != nil {
+		return err
+	}
+
+	err = importBatch(ctx, tx, rows, params.Source, params.Upsert, params.DryRun, job.UserID)
+	if err != nil {
+		return err
+	}
+
+	if params.DryRun {
+		_, err = tx.ExecContext(ctx, `ROLLBACK TO SAVEPOINT import_batch`)
+		if err != nil {
+			return err
+		}
+	}
+
+	var inserted, updated int
+	rowErrors := []ImportRowError{}
+
+	for _, row := range rows {
+		switch {
+		case row.Error != "":
+			rowErrors = append(rowErrors, ImportRowError{Line: row.Line, ExternalID: row.ExternalID, Errors: map[string]string{"external_id": row.Error}})
+		case row.Action == ImportActionInserted:
+			inserted++
+		case row.Action == ImportActionUpdated:
+			updated++
+		}
+	}
+
+	errs, err := json.Marshal(rowErrors)
+	if err != nil {
+		return err
+	}
+
+	query := `
+UPDATE jobs
+SET processed = processed + $2, inserted = inserted + $3, updated = updated + $4, failed = failed + $5,
+  next_item = next_item + $2, errors = errors || $6::jsonb, updated_at = NOW()
+WHERE id = $1
+RETURNING processed, inserted, updated, failed, next_item, updated_at`
+
+	args := []interface{}{job.ID, len(rows), inserted, updated, len(rowErrors), errs}
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&job.Processed, &job.Inserted, &job.Updated, &job.Failed, &job.NextItem, &job.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	job.Errors = append(job.Errors, rowErrors...)
+
+	return nil
+}
+
+// Finish moves a running job to a final status, provided it is still on the
+// worker's attempt. The items of a job which succeeded are no longer needed
+// and are removed.
+func (m JobModel) Finish(job *Job, status, message string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+UPDATE jobs SET status = $2, error = $3, updated_at = NOW()
+WHERE id = $1 AND status = $4 AND attempt = $5
+RETURNING updated_at`
+
+	err = tx.QueryRowContext(ctx, query, job.ID, status, message, JobStatusRunning, job.Attempt).Scan(&job.UpdatedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrJobNotRunning
+		default:
+			return err
+		}
+	}
+
+	if status == JobStatusSucceeded {
+		_, err = tx.ExecContext(ctx, `DELETE FROM job_items WHERE job_id = $1`, job.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	job.Status = status
+	job.Error = message
+
+	return tx.Commit()
+}
+
+// setStatus moves a job to status provided it is currently in one of from,
+// returning ErrInvalidJobStatus otherwise.
+func (m JobModel) setStatus(id int64, status string, from ...string) error {
+	query := `
+UPDATE jobs SET status = $2, error = '', updated_at = NOW()
+WHERE id = $1 AND status = ANY($3)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, status, pq.Array(from))
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrInvalidJobStatus
+	}
+
+	return nil
+}
+
+// Cancel stops a queued or running job. A running job stops before its next
+// batch.
+func (m JobModel) Cancel(id int64) error {
+	return m.setStatus(id, JobStatusCancelled, JobStatusQueued, JobStatusRunning)
+}
+
+// Resume queues a cancelled or failed job again. It carries on from the first
+// batch it had not yet written.
+func (m JobModel) Resume(id int64) error {
+	return m.setStatus(id, JobStatusQueued, JobStatusCancelled, JobStatusFailed)
+}
+
+// Requeue hands a running job back to the queue, for when its worker stops
+// before the job is done.
+func (m JobModel) Requeue(id int64) error {
+	return m.setStatus(id, JobStatusQueued, JobStatusRunning)
+}
+
+// RequeueInterrupted queues every job left running by a previous process,
+// which must have stopped without finishing them.
+func (m JobModel) RequeueInterrupted() (int64, error) {
+	query := `UPDATE jobs SET status = $1, updated_at = NOW() WHERE status = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, JobStatusQueued, JobStatusRunning)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
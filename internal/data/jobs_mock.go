@@ -0,0 +1,43 @@
+package data
+
+type MockJobModel struct{}
+
+func (m MockJobModel) InsertImport(job *Job, rows []*ImportRow) error {
+	return nil
+}
+
+func (m MockJobModel) Get(id int64) (*Job, error) {
+	return nil, nil
+}
+
+func (m MockJobModel) Claim() (*Job, error) {
+	return nil, ErrNoJobsQueued
+}
+
+func (m MockJobModel) NextImportRows(job *Job, limit int) ([]*ImportRow, error) {
+	return nil, nil
+}
+
+func (m MockJobModel) ImportBatch(job *Job, rows []*ImportRow) error {
+	return nil
+}
+
+func (m MockJobModel) Finish(job *Job, status, message string) error {
+	return nil
+}
+
+func (m MockJobModel) Cancel(id int64) error {
+	return nil
+}
+
+func (m MockJobModel) Resume(id int64) error {
+	return nil
+}
+
+func (m MockJobModel) Requeue(id int64) error {
+	return nil
+}
+
+func (m MockJobModel) RequeueInterrupted() (int64, error) {
+	return 0, nil
+}
@@ -0,0 +1,402 @@
+package data
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/mrityunjaygr8/greenlight/internal/validator"
+)
+
+const (
+	ListVisibilityPrivate  = "private"
+	ListVisibilityUnlisted = "unlisted"
+	ListVisibilityPublic   = "public"
+)
+
+var ListVisibilities = []string{ListVisibilityPrivate, ListVisibilityUnlisted, ListVisibilityPublic}
+
+var ErrInvalidListOrder = errors.New("invalid list order")
+
+type List struct {
+	ID          int64        `json:"id"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UserID      int64        `json:"user_id"`
+	Title       string       `json:"title"`
+	Description string       `json:"description,omitempty"`
+	Visibility  string       `json:"visibility"`
+	Slug        string       `json:"slug"`
+	Entries     []*ListEntry `json:"entries,omitempty"`
+	Version     int32        `json:"version"`
+}
+
+type ListEntry struct {
+	MovieID  int64     `json:"movie_id"`
+	Position int32     `json:"position"`
+	Note     string    `json:"note,omitempty"`
+	AddedAt  time.Time `json:"added_at"`
+	Movie    *Movie    `json:"movie,omitempty"`
+}
+
+type ListModelInterface interface {
+	GetAll(userID int64, visibility string, filters Filters) ([]*List, Metadata, error)
+	Insert(list *List) error
+	Get(id int64) (*List, error)
+	GetBySlug(slug string) (*List, error)
+	Update(list *List) error
+	Delete(id int64) error
+	GetEntries(listID int64) ([]*ListEntry, error)
+	AddEntries(listID int64, entries []*ListEntry) error
+	RemoveEntry(listID, movieID int64) error
+	Reorder(listID int64, movieIDs []int64) error
+}
+
+type ListModel struct {
+	DB *sql.DB
+}
+
+// CanBeViewedBy reports whether the user may see the list when addressing it
+// by ID. Unlisted lists are only reachable through their share slug.
+func (l *List) CanBeViewedBy(user *User) bool {
+	return l.Visibility == ListVisibilityPublic || l.UserID == user.ID
+}
+
+func generateListSlug() (string, error) {
+	randomBytes := make([]byte, 10)
+
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(randomBytes)), nil
+}
+
+func ValidateList(v *validator.Validator, list *List) {
+	v.Check(list.Title != "", "title", "must be provided")
+	v.Check(len(list.Title) <= 500, "title", "must not be more than 500 bytes long")
+
+	v.Check(len(list.Description) <= 10_000, "description", "must not be more than 10000 bytes long")
+
+	v.Check(validator.In(list.Visibility, ListVisibilities...), "visibility", "must be one of private, unlisted or public")
+}
+
+func ValidateListEntries(v *validator.Validator, entries []*ListEntry) {
+	v.Check(len(entries) >= 1, "entries", "must contain at least 1 entry")
+	v.Check(len(entries) <= 100, "entries", "must not contain more than 100 entries")
+
+	seen := make(map[int64]bool, len(entries))
+
+	for _, entry := range entries {
+		v.Check(entry.MovieID > 0, "entries", "must only contain valid movie IDs")
+		v.Check(!seen[entry.MovieID], "entries", "must not contain duplicate movies")
+		v.Check(len(entry.Note) <= 1_000, "entries", "notes must not be more than 1000 bytes long")
+
+		seen[entry.MovieID] = true
+	}
+}
+
+func (m ListModel) GetAll(userID int64, visibility string, filters Filters) ([]*List, Metadata, error) {
+	query := fmt.Sprintf(`SELECT count(*) OVER(), id, created_at, user_id, title, description, visibility, slug, version
+FROM lists
+WHERE (user_id = $1 OR $1 = 0)
+AND (visibility = $2 OR $2 = '')
+ORDER BY %s %s, id ASC
+LIMIT $3 OFFSET $4`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID, visibility, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	defer rows.Close()
+
+	totalRecords := 0
+	lists := []*List{}
+
+	for rows.Next() {
+		var list List
+
+		err := rows.Scan(&totalRecords, &list.ID, &list.CreatedAt, &list.UserID, &list.Title, &list.Description, &list.Visibility, &list.Slug, &list.Version)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		lists = append(lists, &list)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return lists, metadata, nil
+}
+
+func (m ListModel) Insert(list *List) error {
+	slug, err := generateListSlug()
+	if err != nil {
+		return err
+	}
+
+	list.Slug = slug
+
+	query := `
+INSERT INTO lists (user_id, title, description, visibility, slug)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, created_at, version`
+
+	args := []interface{}{list.UserID, list.Title, list.Description, list.Visibility, list.Slug}
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&list.ID, &list.CreatedAt, &list.Version)
+}
+
+func (m ListModel) get(where string, arg interface{}) (*List, error) {
+	query := `SELECT id, created_at, user_id, title, description, visibility, slug, version
+FROM lists
+WHERE ` + where
+
+	var list List
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, arg).Scan(&list.ID, &list.CreatedAt, &list.UserID, &list.Title, &list.Description, &list.Visibility, &list.Slug, &list.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &list, nil
+}
+
+func (m ListModel) Get(id int64) (*List, error) {
+	return m.get("id = $1", id)
+}
+
+func (m ListModel) GetBySlug(slug string) (*List, error) {
+	return m.get("slug = $1", slug)
+}
+
+func (m ListModel) Update(list *List) error {
+	query := `UPDATE lists
+SET title = $1, description = $2, visibility = $3, version = version + 1
+WHERE id = $4 AND version = $5
+RETURNING version`
+
+	args := []interface{}{list.Title, list.Description, list.Visibility, list.ID, list.Version}
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&list.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m ListModel) Delete(id int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `DELETE FROM lists WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (m ListModel) GetEntries(listID int64) ([]*ListEntry, error) {
+	query := `
+SELECT list_entries.movie_id, list_entries.position, list_entries.note, list_entries.added_at, movies.title, movies.year, movies.runtime, movies.genres, movies.version
+FROM list_entries
+INNER JOIN movies ON movies.id = list_entries.movie_id
+WHERE list_entries.list_id = $1 AND movies.deleted_at IS NULL
+ORDER BY list_entries.position ASC, list_entries.movie_id ASC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, listID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	entries := []*ListEntry{}
+
+	for rows.Next() {
+		entry := ListEntry{Movie: &Movie{}}
+
+		err := rows.Scan(&entry.MovieID, &entry.Position, &entry.Note, &entry.AddedAt, &entry.Movie.Title, &entry.Movie.Year, &entry.Movie.Runtime, pq.Array(&entry.Movie.Genres), &entry.Movie.Version)
+		if err != nil {
+			return nil, err
+		}
+
+		entry.Movie.ID = entry.MovieID
+		entries = append(entries, &entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// AddEntries appends the entries to the end of the list in the order given.
+// Movies already on the list keep their position and only have their note
+// replaced.
+func (m ListModel) AddEntries(listID int64, entries []*ListEntry) error {
+	movieIDs := make([]int64, len(entries))
+	notes := make([]string, len(entries))
+
+	for i, entry := range entries {
+		movieIDs[i] = entry.MovieID
+		notes[i] = entry.Note
+	}
+
+	query := `
+INSERT INTO list_entries (list_id, movie_id, position, note)
+SELECT $1, x.movie_id, (SELECT COALESCE(max(position), 0) FROM list_entries WHERE list_id = $1) + x.ord, x.note
+FROM unnest($2::bigint[], $3::text[]) WITH ORDINALITY AS x(movie_id, note, ord)
+ON CONFLICT (list_id, movie_id) DO UPDATE SET note = EXCLUDED.note`
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, listID, pq.Array(movieIDs), pq.Array(notes))
+	if err != nil {
+		switch {
+		case err.Error() == `pq: insert or update on table "list_entries" violates foreign key constraint "list_entries_movie_id_fkey"`:
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m ListModel) RemoveEntry(listID, movieID int64) error {
+	query := `DELETE FROM list_entries WHERE list_id = $1 AND movie_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, listID, movieID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// Reorder sets the position of every entry on the list to its index in
+// movieIDs. The slice must name each movie on the list exactly once, otherwise
+// ErrInvalidListOrder is returned and nothing is changed. Movies in the trash
+// are not shown on the list, so they are neither expected nor moved.
+func (m ListModel) Reorder(listID int64, movieIDs []int64) error {
+	if !validator.Unique(movieIDs) {
+		return ErrInvalidListOrder
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var count int
+
+	query := `
+SELECT count(*)
+FROM list_entries
+INNER JOIN movies ON movies.id = list_entries.movie_id
+WHERE list_entries.list_id = $1 AND movies.deleted_at IS NULL`
+
+	err = tx.QueryRowContext(ctx, query, listID).Scan(&count)
+	if err != nil {
+		return err
+	}
+
+	if count != len(movieIDs) {
+		return ErrInvalidListOrder
+	}
+
+	query = `
+UPDATE list_entries
+SET position = x.ord
+FROM unnest($2::bigint[]) WITH ORDINALITY AS x(movie_id, ord), movies
+WHERE list_entries.list_id = $1 AND list_entries.movie_id = x.movie_id
+AND movies.id = list_entries.movie_id AND movies.deleted_at IS NULL`
+
+	result, err := tx.ExecContext(ctx, query, listID, pq.Array(movieIDs))
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if int(rowsAffected) != len(movieIDs) {
+		return ErrInvalidListOrder
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,43 @@
+package data
+
+type MockListModel struct{}
+
+func (m MockListModel) GetAll(userID int64, visibility string, filters Filters) ([]*List, Metadata, error) {
+	return nil, Metadata{}, nil
+}
+
+func (m MockListModel) Insert(list *List) error {
+	return nil
+}
+
+func (m MockListModel) Get(id int64) (*List, error) {
+	return nil, nil
+}
+
+func (m MockListModel) GetBySlug(slug string) (*List, error) {
+	return nil, nil
+}
+
+func (m MockListModel) Update(list *List) error {
+	return nil
+}
+
+func (m MockListModel) Delete(id int64) error {
+	return nil
+}
+
+func (m MockListModel) GetEntries(listID int64) ([]*ListEntry, error) {
+	return nil, nil
+}
+
+func (m MockListModel) AddEntries(listID int64, entries []*ListEntry) error {
+	return nil
+}
+
+func (m MockListModel) RemoveEntry(listID, movieID int64) error {
+	return nil
+}
+
+func (m MockListModel) Reorder(listID int64, movieIDs []int64) error {
+	return nil
+}
@@ -0,0 +1,286 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/mrityunjaygr8/greenlight/internal/validator"
+)
+
+// LanguageTagRX matches the BCP 47 tags movies are translated into: a language
+// with an optional script and region, such as "pt", "pt-BR" or "zh-Hant-TW".
+// Variants and extensions are not supported.
+var LanguageTagRX = regexp.MustCompile("^[a-zA-Z]{2,3}(-[a-zA-Z]{4})?(-([a-zA-Z]{2}|[0-9]{3}))?$")
+
+// CountryRX matches ISO 3166-1 alpha-2 country codes.
+var CountryRX = regexp.MustCompile("^[A-Z]{2}$")
+
+// MovieTranslation is a movie's title and synopsis in one language.
+type MovieTranslation struct {
+	Language  string    `json:"language"`
+	Title     string    `json:"title"`
+	Synopsis  string    `json:"synopsis,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// MovieRelease is when a movie came out in one country, and the age rating it
+// was given there. Either may be left empty when unknown.
+type MovieRelease struct {
+	Country       string `json:"country"`
+	ReleaseDate   string `json:"release_date,omitempty"`
+	Certification string `json:"certification,omitempty"`
+}
+
+type LocalizationModelInterface interface {
+	GetTranslations(movieIDs []int64) (map[int64][]*MovieTranslation, error)
+	PutTranslation(movieID int64, translation *MovieTranslation) error
+	DeleteTranslation(movieID int64, language string) error
+	GetReleases(movieIDs []int64) (map[int64][]*MovieRelease, error)
+	SetReleases(movieID int64, releases []*MovieRelease) error
+}
+
+type LocalizationModel struct {
+	DB *sql.DB
+}
+
+// CanonicalLanguageTag puts a language tag into its conventional case, so
+// that "PT-br" and "pt-BR" are stored and matched as the same tag.
+func CanonicalLanguageTag(tag string) string {
+	subtags := strings.Split(tag, "-")
+
+	for i, subtag := range subtags {
+		switch {
+		case i == 0:
+			subtags[i] = strings.ToLower(subtag)
+		case len(subtag) == 4:
+			subtags[i] = strings.ToUpper(subtag[:1]) + strings.ToLower(subtag[1:])
+		default:
+			subtags[i] = strings.ToUpper(subtag)
+		}
+	}
+
+	return strings.Join(subtags, "-")
+}
+
+func ValidateMovieTranslation(v *validator.Validator, translation *MovieTranslation) {
+	v.Check(validator.Matches(translation.Language, LanguageTagRX), "language", "must be a BCP 47 language tag")
+
+	v.Check(strings.TrimSpace(translation.Title) != "", "title", "must be provided")
+	v.Check(len(translation.Title) <= 500, "title", "must not be more than 500 bytes long")
+
+	v.Check(len(translation.Synopsis) <= 10_000, "synopsis", "must not be more than 10000 bytes long")
+}
+
+func ValidateMovieReleases(v *validator.Validator, releases []*MovieRelease) {
+	v.Check(len(releases) <= 250, "releases", "must not contain more than 250 countries")
+
+	countries := make([]string, len(releases))
+
+	for i, release := range releases {
+		countries[i] = release.Country
+
+		if !validator.Matches(release.Country, CountryRX) {
+			v.AddError("releases", "countries must be ISO 3166-1 alpha-2 codes")
+		}
+
+		if release.ReleaseDate != "" {
+			_, err := time.Parse("2006-01-02", release.ReleaseDate)
+			if err != nil {
+				v.AddError("releases", "release dates must be in YYYY-MM-DD format")
+			}
+		}
+
+		if len(release.Certification) > 20 {
+			v.AddError("releases", "certifications must not be more than 20 bytes long")
+		}
+	}
+
+	v.Check(validator.Unique(countries), "releases", "must not contain duplicate countries")
+}
+
+// GetTranslations fetches the translations of several movies in one query,
+// keyed by movie ID and ordered by language.
+func (m LocalizationModel) GetTranslations(movieIDs []int64) (map[int64][]*MovieTranslation, error) {
+	query := `
+SELECT movie_id, language, title, synopsis, updated_at
+FROM movie_translations
+WHERE movie_id = ANY($1)
+ORDER BY movie_id ASC, language ASC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(movieIDs))
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	translations := make(map[int64][]*MovieTranslation)
+
+	for rows.Next() {
+		var movieID int64
+		var translation MovieTranslation
+
+		err := rows.Scan(&movieID, &translation.Language, &translation.Title, &translation.Synopsis, &translation.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		translations[movieID] = append(translations[movieID], &translation)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return translations, nil
+}
+
+// PutTranslation adds or replaces the movie's translation into the
+// translation's language.
+func (m LocalizationModel) PutTranslation(movieID int64, translation *MovieTranslation) error {
+	query := `
+INSERT INTO movie_translations (movie_id, language, title, synopsis)
+SELECT $1, $2, $3, $4
+FROM movies
+WHERE id = $1 AND deleted_at IS NULL
+ON CONFLICT (movie_id, language) DO UPDATE
+SET title = EXCLUDED.title, synopsis = EXCLUDED.synopsis, updated_at = NOW()
+RETURNING updated_at`
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, movieID, translation.Language, translation.Title, translation.Synopsis).Scan(&translation.UpdatedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m LocalizationModel) DeleteTranslation(movieID int64, language string) error {
+	query := `DELETE FROM movie_translations WHERE movie_id = $1 AND language = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, movieID, language)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// GetReleases fetches the releases of several movies in one query, keyed by
+// movie ID and ordered by country.
+func (m LocalizationModel) GetReleases(movieIDs []int64) (map[int64][]*MovieRelease, error) {
+	query := `
+SELECT movie_id, country, COALESCE(to_char(release_date, 'YYYY-MM-DD'), ''), certification
+FROM movie_releases
+WHERE movie_id = ANY($1)
+ORDER BY movie_id ASC, country ASC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(movieIDs))
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	releases := make(map[int64][]*MovieRelease)
+
+	for rows.Next() {
+		var movieID int64
+		var release MovieRelease
+
+		err := rows.Scan(&movieID, &release.Country, &release.ReleaseDate, &release.Certification)
+		if err != nil {
+			return nil, err
+		}
+
+		releases[movieID] = append(releases[movieID], &release)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return releases, nil
+}
+
+// SetReleases replaces all of a movie's releases.
+func (m LocalizationModel) SetReleases(movieID int64, releases []*MovieRelease) error {
+	countries := []string{}
+	dates := []string{}
+	certifications := []string{}
+
+	for _, release := range releases {
+		countries = append(countries, release.Country)
+		dates = append(dates, release.ReleaseDate)
+		certifications = append(certifications, release.Certification)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var id int64
+
+	err = tx.QueryRowContext(ctx, `SELECT id FROM movies WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, movieID).Scan(&id)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM movie_releases WHERE movie_id = $1`, movieID)
+	if err != nil {
+		return err
+	}
+
+	query := `
+INSERT INTO movie_releases (movie_id, country, release_date, certification)
+SELECT $1, v.country, NULLIF(v.release_date, '')::date, v.certification
+FROM unnest($2::text[], $3::text[], $4::text[]) AS v(country, release_date, certification)`
+
+	_, err = tx.ExecContext(ctx, query, movieID, pq.Array(countries), pq.Array(dates), pq.Array(certifications))
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
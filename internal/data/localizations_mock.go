@@ -0,0 +1,23 @@
+package data
+
+type MockLocalizationModel struct{}
+
+func (m MockLocalizationModel) GetTranslations(movieIDs []int64) (map[int64][]*MovieTranslation, error) {
+	return nil, nil
+}
+
+func (m MockLocalizationModel) PutTranslation(movieID int64, translation *MovieTranslation) error {
+	return nil
+}
+
+func (m MockLocalizationModel) DeleteTranslation(movieID int64, language string) error {
+	return nil
+}
+
+func (m MockLocalizationModel) GetReleases(movieIDs []int64) (map[int64][]*MovieRelease, error) {
+	return nil, nil
+}
+
+func (m MockLocalizationModel) SetReleases(movieID int64, releases []*MovieRelease) error {
+	return nil
+}
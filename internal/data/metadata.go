@@ -3,11 +3,13 @@ package data
 import "math"
 
 type Metadata struct {
-	CurrentPage  int `json:"current_page"`
-	PageSize     int `json:"page_size"`
-	FirstPage    int `json:"first_page"`
-	LastPage     int `json:"last_page"`
-	TotalRecords int `json:"total_records"`
+	CurrentPage  int    `json:"current_page"`
+	PageSize     int    `json:"page_size"`
+	FirstPage    int    `json:"first_page"`
+	LastPage     int    `json:"last_page"`
+	TotalRecords int    `json:"total_records"`
+	NextCursor   string `json:"next_cursor,omitempty"`
+	PrevCursor   string `json:"prev_cursor,omitempty"`
 }
 
 func calculateMetadata(totalRecords, page, pageSize int) Metadata {
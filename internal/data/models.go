@@ -9,26 +9,68 @@ var ErrRecordNotFound = errors.New("record not found")
 var ErrEditConflict = errors.New("edit conflict")
 
 type Models struct {
-	Movies      MovieModelInterface
-	Users       UserModelInterface
-	Tokens      TokenModelInterface
-	Permissions PermissionModelInterface
+	Movies        MovieModelInterface
+	Users         UserModelInterface
+	Tokens        TokenModelInterface
+	Permissions   PermissionModelInterface
+	Audit         AuditModelInterface
+	Reviews       ReviewModelInterface
+	People        PersonModelInterface
+	Credits       CreditModelInterface
+	Watchlist     WatchlistModelInterface
+	History       HistoryModelInterface
+	Lists         ListModelInterface
+	Genres        GenreModelInterface
+	Revisions     RevisionModelInterface
+	Jobs          JobModelInterface
+	Images        ImageModelInterface
+	ExternalIDs   ExternalIDModelInterface
+	Localizations LocalizationModelInterface
+	Collections   CollectionModelInterface
 }
 
 func NewModels(db *sql.DB) Models {
 	return Models{
-		Movies:      MovieModel{DB: db},
-		Users:       UserModel{DB: db},
-		Tokens:      TokenModel{DB: db},
-		Permissions: PermissionModel{DB: db},
+		Movies:        MovieModel{DB: db},
+		Users:         UserModel{DB: db},
+		Tokens:        TokenModel{DB: db},
+		Permissions:   PermissionModel{DB: db},
+		Audit:         AuditModel{DB: db},
+		Reviews:       ReviewModel{DB: db},
+		People:        PersonModel{DB: db},
+		Credits:       CreditModel{DB: db},
+		Watchlist:     WatchlistModel{DB: db},
+		History:       HistoryModel{DB: db},
+		Lists:         ListModel{DB: db},
+		Genres:        GenreModel{DB: db},
+		Revisions:     RevisionModel{DB: db},
+		Jobs:          JobModel{DB: db},
+		Images:        ImageModel{DB: db},
+		ExternalIDs:   ExternalIDModel{DB: db},
+		Localizations: LocalizationModel{DB: db},
+		Collections:   CollectionModel{DB: db},
 	}
 }
 
 func NewMockModels(db *sql.DB) Models {
 	return Models{
-		Movies:      MockMovieModel{},
-		Users:       MockUsersModel{},
-		Tokens:      MockTokenModel{},
-		Permissions: MockPermissionsModel{},
+		Movies:        MockMovieModel{},
+		Users:         MockUsersModel{},
+		Tokens:        MockTokenModel{},
+		Permissions:   MockPermissionsModel{},
+		Audit:         MockAuditModel{},
+		Reviews:       MockReviewModel{},
+		People:        MockPersonModel{},
+		Credits:       MockCreditModel{},
+		Watchlist:     MockWatchlistModel{},
+		History:       MockHistoryModel{},
+		Lists:         MockListModel{},
+		Genres:        MockGenreModel{},
+		Revisions:     MockRevisionModel{},
+		Jobs:          MockJobModel{},
+		Images:        MockImageModel{},
+		ExternalIDs:   MockExternalIDModel{},
+		Localizations: MockLocalizationModel{},
+		Collections:   MockCollectionModel{},
 	}
 }
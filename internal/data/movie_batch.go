@@ -0,0 +1,120 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+const (
+	MovieBatchCreate = "create"
+	MovieBatchUpdate = "update"
+	MovieBatchDelete = "delete"
+)
+
+const movieBatchTimeout = 30 * time.Second
+
+// MovieBatchOp is one write in a batch. For updates and deletes, Movie.Version
+// is the version the movie must still be at; a delete with no version removes
+// the movie whatever its version. Batch sets Err to ErrRecordNotFound or
+// ErrEditConflict when the operation could not be applied.
+type MovieBatchOp struct {
+	Op    string
+	Movie *Movie
+	Err   error
+}
+
+// Batch applies the operations in order within one transaction, crediting any
+// revisions to userID. When atomic, the first operation to fail stops the
+// batch and nothing is written. Otherwise each operation runs in its own
+// savepoint, so failures are rolled back individually and the rest commit.
+// Only unexpected errors are returned; per-operation failures are left in Err.
+func (m MovieModel) Batch(ops []*MovieBatchOp, atomic bool, userID int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), movieBatchTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, op := range ops {
+		if !atomic {
+			_, err = tx.ExecContext(ctx, "SAVEPOINT movie_batch_op")
+			if err != nil {
+				return err
+			}
+		}
+
+		switch op.Op {
+		case MovieBatchCreate:
+			err = insertMovie(ctx, tx, op.Movie, userID)
+		case MovieBatchUpdate:
+			err = updateMovie(ctx, tx, op.Movie, userID)
+		case MovieBatchDelete:
+			err = trashMovie(ctx, tx, op.Movie.ID, op.Movie.Version)
+		default:
+			return errors.New("unknown movie batch operation: " + op.Op)
+		}
+
+		if err != nil && !errors.Is(err, ErrRecordNotFound) && !errors.Is(err, ErrEditConflict) {
+			return err
+		}
+
+		op.Err = err
+
+		switch {
+		case op.Err != nil && atomic:
+			return nil
+		case op.Err != nil:
+			_, err = tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT movie_batch_op")
+		case !atomic:
+			_, err = tx.ExecContext(ctx, "RELEASE SAVEPOINT movie_batch_op")
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// trashMovie moves a movie to the trash, but only while it is still at
+// version, unless version is zero.
+func trashMovie(ctx context.Context, tx *sql.Tx, id int64, version int32) error {
+	query := `
+UPDATE movies SET deleted_at = NOW()
+WHERE id = $1 AND deleted_at IS NULL AND ($2 = 0 OR version = $2)`
+
+	result, err := tx.ExecContext(ctx, query, id, version)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected > 0 {
+		return nil
+	}
+
+	// Nothing matched, either because the movie is gone or because it has
+	// moved on to another version.
+	var exists bool
+
+	err = tx.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM movies WHERE id = $1 AND deleted_at IS NULL)`, id).Scan(&exists)
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		return ErrEditConflict
+	}
+
+	return ErrRecordNotFound
+}
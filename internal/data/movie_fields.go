@@ -0,0 +1,91 @@
+package data
+
+import (
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/mrityunjaygr8/greenlight/internal/validator"
+)
+
+const (
+	MovieIncludeCredits      = "credits"
+	MovieIncludeTranslations = "translations"
+	MovieIncludeReleases     = "releases"
+	MovieIncludeCollection   = "collection"
+)
+
+// MovieFieldSafelist holds the movie fields a client may ask for with fields=.
+// in_watchlist is computed, and the images, external IDs and the localized
+// title and synopsis are loaded separately, so they have no column.
+var MovieFieldSafelist = []string{"id", "title", "original_title", "language", "synopsis", "year", "runtime", "genres", "average_rating", "rating_count", "in_watchlist", "poster", "backdrop", "external_ids", "version"}
+
+// MovieIncludeSafelist holds the related resources a client may embed in a
+// movie with include=.
+var MovieIncludeSafelist = []string{MovieIncludeCredits, MovieIncludeTranslations, MovieIncludeReleases, MovieIncludeCollection}
+
+// movieColumns lists the selectable movie columns in SELECT order, along with
+// where each one is scanned to.
+var movieColumns = []struct {
+	name string
+	dest func(movie *Movie) interface{}
+}{
+	{"id", func(movie *Movie) interface{} { return &movie.ID }},
+	{"created_at", func(movie *Movie) interface{} { return &movie.CreatedAt }},
+	{"title", func(movie *Movie) interface{} { return &movie.Title }},
+	{"year", func(movie *Movie) interface{} { return &movie.Year }},
+	{"runtime", func(movie *Movie) interface{} { return &movie.Runtime }},
+	{"genres", func(movie *Movie) interface{} { return pq.Array(&movie.Genres) }},
+	{"average_rating", func(movie *Movie) interface{} { return &movie.AverageRating }},
+	{"rating_count", func(movie *Movie) interface{} { return &movie.RatingCount }},
+	{"version", func(movie *Movie) interface{} { return &movie.Version }},
+}
+
+func ValidateMovieFields(v *validator.Validator, fields, include []string) {
+	for _, field := range fields {
+		v.Check(validator.In(field, MovieFieldSafelist...), "fields", "invalid field value")
+	}
+
+	v.Check(validator.Unique(fields), "fields", "must not contain duplicate values")
+
+	for _, name := range include {
+		v.Check(validator.In(name, MovieIncludeSafelist...), "include", "invalid include value")
+	}
+
+	v.Check(validator.Unique(include), "include", "must not contain duplicate values")
+}
+
+// HasField reports whether field is part of the requested sparse fieldset. An
+// empty fieldset means every field.
+func HasField(fields []string, field string) bool {
+	return len(fields) == 0 || validator.In(field, fields...)
+}
+
+// selectMovieColumns returns the column list for a movie SELECT restricted to
+// fields, plus the columns in required which are needed regardless, and a
+// function giving the matching scan destinations for a movie. The id and
+// version are always selected, since the version backs the movie's ETag.
+func selectMovieColumns(fields []string, required ...string) (string, func(movie *Movie) []interface{}) {
+	var names []string
+	var dests []func(movie *Movie) interface{}
+
+	for _, column := range movieColumns {
+		// The original title is the title column, kept aside when the title
+		// is swapped for a translation.
+		selected := HasField(fields, column.name) || column.name == "title" && HasField(fields, "original_title")
+
+		if column.name == "id" || column.name == "version" || selected || validator.In(column.name, required...) {
+			names = append(names, column.name)
+			dests = append(dests, column.dest)
+		}
+	}
+
+	scan := func(movie *Movie) []interface{} {
+		targets := make([]interface{}, len(dests))
+		for i, dest := range dests {
+			targets[i] = dest(movie)
+		}
+		return targets
+	}
+
+	return strings.Join(names, ", "), scan
+}
@@ -0,0 +1,129 @@
+package data
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/mrityunjaygr8/greenlight/internal/validator"
+)
+
+// MovieFilters narrows a movie listing. Zero values mean "no restriction".
+type MovieFilters struct {
+	Title         string
+	Language      string
+	Fuzzy         bool
+	Genres        []string
+	GenresAny     []string
+	GenresExclude []string
+	YearMin       int
+	YearMax       int
+	RuntimeMin    int
+	RuntimeMax    int
+	CreatedAfter  time.Time
+	IDs           []int64
+	PersonID      int64
+	CollectionID  int64
+	Director      string
+}
+
+func ValidateMovieFilters(v *validator.Validator, f MovieFilters) {
+	v.Check(len(f.Title) <= 500, "title", "must not be more than 500 bytes long")
+	if f.Language != "" {
+		v.Check(validator.In(f.Language, TitleSearchLanguages...), "search_lang", "unsupported search language")
+	}
+
+	v.Check(len(f.Genres) <= 20, "genres", "must not contain more than 20 genres")
+	v.Check(len(f.GenresAny) <= 20, "genres_any", "must not contain more than 20 genres")
+	v.Check(len(f.GenresExclude) <= 20, "genres_exclude", "must not contain more than 20 genres")
+
+	if f.YearMin != 0 {
+		v.Check(f.YearMin >= 1888, "year_min", "must be greater than 1888")
+	}
+	if f.YearMax != 0 {
+		v.Check(f.YearMax >= 1888, "year_max", "must be greater than 1888")
+	}
+	if f.YearMin != 0 && f.YearMax != 0 {
+		v.Check(f.YearMin <= f.YearMax, "year_min", "must not be greater than year_max")
+	}
+
+	v.Check(f.RuntimeMin >= 0, "runtime_min", "must not be negative")
+	v.Check(f.RuntimeMax >= 0, "runtime_max", "must not be negative")
+	if f.RuntimeMin != 0 && f.RuntimeMax != 0 {
+		v.Check(f.RuntimeMin <= f.RuntimeMax, "runtime_min", "must not be greater than runtime_max")
+	}
+
+	v.Check(f.CreatedAfter.Before(time.Now()), "created_after", "must be in the past")
+
+	v.Check(len(f.IDs) <= 100, "ids", "must not contain more than 100 IDs")
+	for _, id := range f.IDs {
+		v.Check(id > 0, "ids", "must only contain positive integers")
+	}
+
+	v.Check(f.PersonID >= 0, "person_id", "must not be negative")
+	v.Check(f.CollectionID >= 0, "collection_id", "must not be negative")
+}
+
+func (f MovieFilters) where() *whereClause {
+	w := &whereClause{}
+
+	w.and("deleted_at IS NULL")
+
+	if f.Title != "" {
+		f.titleCondition(w)
+	}
+
+	if len(f.Genres) > 0 {
+		w.and("genres @> ?", pq.Array(f.Genres))
+	}
+
+	if len(f.GenresAny) > 0 {
+		w.and("genres && ?", pq.Array(f.GenresAny))
+	}
+
+	if len(f.GenresExclude) > 0 {
+		w.and("NOT genres && ?", pq.Array(f.GenresExclude))
+	}
+
+	if f.YearMin != 0 {
+		w.and("year >= ?", f.YearMin)
+	}
+
+	if f.YearMax != 0 {
+		w.and("year <= ?", f.YearMax)
+	}
+
+	if f.RuntimeMin != 0 {
+		w.and("runtime >= ?", f.RuntimeMin)
+	}
+
+	if f.RuntimeMax != 0 {
+		w.and("runtime <= ?", f.RuntimeMax)
+	}
+
+	if !f.CreatedAfter.IsZero() {
+		w.and("created_at > ?", f.CreatedAfter)
+	}
+
+	if len(f.IDs) > 0 {
+		w.and("id = ANY(?)", pq.Array(f.IDs))
+	}
+
+	if f.PersonID != 0 {
+		w.and("id IN (SELECT movie_id FROM movie_credits WHERE person_id = ?)", f.PersonID)
+	}
+
+	if f.CollectionID != 0 {
+		w.and("id IN (SELECT movie_id FROM collection_movies WHERE collection_id = ?)", f.CollectionID)
+	}
+
+	if f.Director != "" {
+		w.and(`id IN (
+    SELECT movie_credits.movie_id FROM movie_credits
+    INNER JOIN people ON people.id = movie_credits.person_id
+    WHERE movie_credits.role = 'director'
+    AND to_tsvector('simple', people.name) @@ plainto_tsquery('simple', ?)
+  )`, f.Director)
+	}
+
+	return w
+}
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/lib/pq"
@@ -12,56 +13,150 @@ import (
 )
 
 type Movie struct {
-	ID        int64     `json:"id"`
-	CreatedAt time.Time `json:"-"`
-	Title     string    `json:"title"`
-	Year      int32     `json:"year,omitempty"`
-	Runtime   Runtime   `json:"runtime,omitempty"`
-	Genres    []string  `json:"genres,omitempty"`
-	Version   int32     `json:"version"`
+	ID            int64               `json:"id"`
+	CreatedAt     time.Time           `json:"-"`
+	Title         string              `json:"title"`
+	OriginalTitle string              `json:"original_title,omitempty"`
+	Language      string              `json:"language,omitempty"`
+	Synopsis      string              `json:"synopsis,omitempty"`
+	Year          int32               `json:"year,omitempty"`
+	Runtime       Runtime             `json:"runtime,omitempty"`
+	Genres        []string            `json:"genres,omitempty"`
+	AverageRating float64             `json:"average_rating"`
+	RatingCount   int32               `json:"rating_count"`
+	Credits       []*Credit           `json:"credits,omitempty"`
+	Translations  []*MovieTranslation `json:"translations,omitempty"`
+	Releases      []*MovieRelease     `json:"releases,omitempty"`
+	Collection    *MovieCollection    `json:"collection,omitempty"`
+	InWatchlist   *bool               `json:"in_watchlist,omitempty"`
+	Poster        *MovieImage         `json:"poster,omitempty"`
+	Backdrop      *MovieImage         `json:"backdrop,omitempty"`
+	ExternalIDs   map[string]string   `json:"external_ids,omitempty"`
+	DeletedAt     *time.Time          `json:"deleted_at,omitempty"`
+	Version       int32               `json:"version"`
 }
 
 type MovieModelInterface interface {
-	GetAll(title string, genres []string, filters Filters) ([]*Movie, Metadata, error)
-	Insert(movie *Movie) error
+	GetAll(movieFilters MovieFilters, filters Filters, fields []string) ([]*Movie, Metadata, error)
+	Facets(movieFilters MovieFilters, facets []string) (map[string][]FacetCount, error)
+	Export(ctx context.Context, movieFilters MovieFilters, filters Filters, fields []string, snapshot bool, fn func(movies []*Movie) error) error
+	Autocomplete(q string, limit int) ([]*MovieSuggestion, error)
+	Insert(movie *Movie, userID int64) error
 	Get(id int64) (*Movie, error)
-	Update(movie *Movie) error
-	Delete(id int64) error
+	GetFields(id int64, fields []string) (*Movie, error)
+	Update(movie *Movie, userID int64) error
+	Delete(id int64, version int32) error
+	Batch(ops []*MovieBatchOp, atomic bool, userID int64) error
+	FindDuplicates(criteria DuplicateCriteria, filters Filters) ([]*DuplicateCandidate, Metadata, error)
+	Merge(sourceID, targetID, userID int64) (*Movie, error)
+	GetFeatures(ctx context.Context) ([]*MovieFeatures, error)
+	GetUserSignals(userID int64, limit int) ([]*UserSignal, error)
+	Stats(period string) (*MovieStats, error)
+	GetTrash(filters Filters) ([]*Movie, Metadata, error)
+	Restore(id int64) error
+	Purge(id int64) error
+	PurgeExpired(deletedBefore time.Time) (int64, error)
 }
 
 type MovieModel struct {
 	DB *sql.DB
 }
 
-func (m MovieModel) GetAll(title string, genres []string, filters Filters) ([]*Movie, Metadata, error) {
-	query := fmt.Sprintf(`SELECT count(*) OVER(), id, created_at, title, year, runtime, genres, version 
-  FROM movies
-  WHERE (to_tsvector('simple', title) @@ plainto_tsquery('simple', $1) OR $1 = '')
-  AND (genres @> $2 OR $2 = '{}')
-  ORDER BY %s %s, id ASC
-  LIMIT $3 OFFSET $4`, filters.sortColumn(), filters.sortDirection())
+// GetAll lists the movies matching movieFilters. Only the columns behind fields
+// are fetched, along with whatever the sort needs; an empty fields fetches all.
+func (m MovieModel) GetAll(movieFilters MovieFilters, filters Filters, fields []string) ([]*Movie, Metadata, error) {
+	c, err := filters.cursor()
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	where := movieFilters.where()
 
 	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
 	defer cancel()
 
-	rows, err := m.DB.QueryContext(ctx, query, title, pq.Array(genres), filters.limit(), filters.offset())
+	// With a cursor the window count would only cover the rows after it, so
+	// the total has to come from a separate query.
+	totalRecords := 0
+	totalColumn := "0"
+
+	if filters.IncludeTotal {
+		if c == nil {
+			totalColumn = "count(*) OVER()"
+		} else {
+			err := m.DB.QueryRowContext(ctx, "SELECT count(*) FROM movies "+where.String(), where.args...).Scan(&totalRecords)
+			if err != nil {
+				return nil, Metadata{}, err
+			}
+		}
+	}
+
+	column := filters.sortColumn()
+	direction := filters.sortDirection()
+
+	// Relevance is computed per query rather than stored, so it always sorts
+	// best match first and cannot be paged through with a cursor.
+	keyset := true
+	if column == "relevance" {
+		column = movieFilters.relevance(where)
+		direction = "DESC"
+		keyset = false
+	}
+
+	if c != nil {
+		operator := ">"
+		if direction == "DESC" {
+			operator = "<"
+		}
+
+		if c.Backward {
+			operator, direction = reverseKeyset(operator, direction)
+		}
+
+		where.and(fmt.Sprintf("(%s, id) %s (?, ?)", column, operator), c.Value, c.ID)
+	}
+
+	required := []string{}
+	if keyset {
+		required = append(required, column)
+	}
+
+	columns, scan := selectMovieColumns(fields, required...)
+
+	conditions := where.String()
+
+	// One extra row is fetched to find out whether another page follows.
+	limit := where.param(filters.limit() + 1)
+	offset := where.param(filters.offset())
+
+	query := fmt.Sprintf(`SELECT %s, %s
+  FROM movies
+  %s
+  ORDER BY %s %s, id %s
+  LIMIT %s OFFSET %s`, totalColumn, columns, conditions, column, direction, direction, limit, offset)
+
+	rows, err := m.DB.QueryContext(ctx, query, where.args...)
 	if err != nil {
 		return nil, Metadata{}, err
 	}
 
 	defer rows.Close()
 
-	totalRecords := 0
 	movies := []*Movie{}
 
 	for rows.Next() {
 		var movie Movie
-		err := rows.Scan(&totalRecords, &movie.ID, &movie.CreatedAt, &movie.Title, &movie.Year, &movie.Runtime, pq.Array(&movie.Genres), &movie.Version)
+		var total int
+		err := rows.Scan(append([]interface{}{&total}, scan(&movie)...)...)
 
 		if err != nil {
 			return nil, Metadata{}, err
 		}
 
+		if c == nil && filters.IncludeTotal {
+			totalRecords = total
+		}
+
 		movies = append(movies, &movie)
 	}
 
@@ -69,34 +164,149 @@ func (m MovieModel) GetAll(title string, genres []string, filters Filters) ([]*M
 		return nil, Metadata{}, err
 	}
 
-	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	hasMore := len(movies) > filters.limit()
+	if hasMore {
+		movies = movies[:filters.limit()]
+	}
+
+	if c != nil && c.Backward {
+		for i, j := 0, len(movies)-1; i < j; i, j = i+1, j-1 {
+			movies[i], movies[j] = movies[j], movies[i]
+		}
+	}
+
+	var metadata Metadata
+
+	switch {
+	case filters.IncludeTotal:
+		metadata = calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	default:
+		metadata = Metadata{CurrentPage: filters.Page, PageSize: filters.PageSize, FirstPage: 1}
+	}
+
+	if c != nil {
+		metadata.CurrentPage = 0
+	}
+
+	// Walking backwards, the page we came from always follows; walking
+	// forwards, there is a previous page whenever we started from a cursor.
+	hasNext, hasPrev := hasMore, c != nil
+	if c != nil && c.Backward {
+		hasNext, hasPrev = true, hasMore
+	}
+
+	if keyset && len(movies) > 0 {
+		first, last := movies[0], movies[len(movies)-1]
+
+		if hasNext {
+			metadata.NextCursor = encodeCursor(filters.CursorSecret, cursor{Sort: filters.Sort, Value: movieSortValue(last, column), ID: last.ID})
+		}
+
+		if hasPrev {
+			metadata.PrevCursor = encodeCursor(filters.CursorSecret, cursor{Sort: filters.Sort, Value: movieSortValue(first, column), ID: first.ID, Backward: true})
+		}
+	}
 
 	return movies, metadata, nil
 }
 
-func (m MovieModel) Insert(movie *Movie) error {
+func reverseKeyset(operator, direction string) (string, string) {
+	if operator == ">" {
+		operator = "<"
+	} else {
+		operator = ">"
+	}
+
+	if direction == "ASC" {
+		direction = "DESC"
+	} else {
+		direction = "ASC"
+	}
+
+	return operator, direction
+}
+
+// movieSortValue returns the value of the sort column for the movie in the
+// text form Postgres accepts back as a query parameter. It must handle every
+// column in the movies SortSafelist.
+func movieSortValue(movie *Movie, column string) string {
+	switch column {
+	case "id":
+		return strconv.FormatInt(movie.ID, 10)
+	case "title":
+		return movie.Title
+	case "year":
+		return strconv.FormatInt(int64(movie.Year), 10)
+	case "runtime":
+		return strconv.FormatInt(int64(movie.Runtime), 10)
+	case "average_rating":
+		return strconv.FormatFloat(movie.AverageRating, 'f', -1, 64)
+	case "rating_count":
+		return strconv.FormatInt(int64(movie.RatingCount), 10)
+	}
+
+	panic("no cursor value for sort column: " + column)
+}
+
+// Insert creates the movie along with its first revision, credited to userID.
+func (m MovieModel) Insert(movie *Movie, userID int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = insertMovie(ctx, tx, movie, userID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func insertMovie(ctx context.Context, tx *sql.Tx, movie *Movie, userID int64) error {
 	query := `
 INSERT INTO movies (title, year, runtime, genres)
 VALUES ($1, $2, $3, $4)
 RETURNING id, created_at, version`
 
 	args := []interface{}{movie.Title, movie.Year, movie.Runtime, pq.Array(movie.Genres)}
-	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
-	defer cancel()
-	return m.DB.QueryRowContext(ctx, query, args...).Scan(&movie.ID, &movie.CreatedAt, &movie.Version)
+
+	err := tx.QueryRowContext(ctx, query, args...).Scan(&movie.ID, &movie.CreatedAt, &movie.Version)
+	if err != nil {
+		return err
+	}
+
+	err = replaceMovieGenres(ctx, tx, movie.ID, movie.Genres)
+	if err != nil {
+		return err
+	}
+
+	return recordMovieRevisions(ctx, tx, []int64{movie.ID}, userID)
 }
 
 func (m MovieModel) Get(id int64) (*Movie, error) {
-	query := `SELECT  id, created_at, title, year, runtime, genres, version
+	return m.GetFields(id, nil)
+}
+
+// GetFields fetches a movie with only the columns behind fields populated. An
+// empty fields fetches the whole movie.
+func (m MovieModel) GetFields(id int64, fields []string) (*Movie, error) {
+	columns, scan := selectMovieColumns(fields)
+
+	query := fmt.Sprintf(`SELECT %s
 FROM movies
-WHERE id = $1`
+WHERE id = $1 AND deleted_at IS NULL`, columns)
 
 	var movie Movie
 
 	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
 	defer cancel()
 
-	err := m.DB.QueryRowContext(ctx, query, id).Scan(&movie.ID, &movie.CreatedAt, &movie.Title, &movie.Year, &movie.Runtime, pq.Array(&movie.Genres), &movie.Version)
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(scan(&movie)...)
 
 	if err != nil {
 		switch {
@@ -109,10 +319,30 @@ WHERE id = $1`
 	return &movie, nil
 }
 
-func (m MovieModel) Update(movie *Movie) error {
+// Update saves the movie as a new version, provided nobody else has changed it
+// since it was read, and records the revision as made by userID.
+func (m MovieModel) Update(movie *Movie, userID int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = updateMovie(ctx, tx, movie, userID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func updateMovie(ctx context.Context, tx *sql.Tx, movie *Movie, userID int64) error {
 	query := `UPDATE movies
 SET title = $1, year = $2, runtime = $3, genres = $4, version = version + 1
-WHERE id = $5 AND version =$6
+WHERE id = $5 AND version =$6 AND deleted_at IS NULL
 RETURNING version`
 
 	args := []interface{}{
@@ -124,10 +354,7 @@ RETURNING version`
 		movie.Version,
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
-	defer cancel()
-
-	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&movie.Version)
+	err := tx.QueryRowContext(ctx, query, args...).Scan(&movie.Version)
 
 	if err != nil {
 		switch {
@@ -138,31 +365,37 @@ RETURNING version`
 		}
 	}
 
-	return nil
+	err = replaceMovieGenres(ctx, tx, movie.ID, movie.Genres)
+	if err != nil {
+		return err
+	}
+
+	return recordMovieRevisions(ctx, tx, []int64{movie.ID}, userID)
 }
 
-func (m MovieModel) Delete(id int64) error {
+// Delete moves the movie to the trash, where it stays restorable until it is
+// purged. A non-zero version makes the delete conditional: if the movie has
+// been edited since, ErrEditConflict is returned and it is left alone.
+func (m MovieModel) Delete(id int64, version int32) error {
 	if id < 1 {
 		return ErrRecordNotFound
 	}
-	query := `DELETE FROM movies WHERE id = $1`
+
 	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
 	defer cancel()
-	result, err := m.DB.ExecContext(ctx, query, id)
+
+	tx, err := m.DB.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
-	rowsAffected, err := result.RowsAffected()
+	err = trashMovie(ctx, tx, id, version)
 	if err != nil {
 		return err
 	}
 
-	if rowsAffected == 0 {
-		return ErrRecordNotFound
-	}
-
-	return nil
+	return tx.Commit()
 }
 
 func ValidateMovie(v *validator.Validator, movie *Movie) {
@@ -1,12 +1,29 @@
 package data
 
+import (
+	"context"
+	"time"
+)
+
 type MockMovieModel struct{}
 
-func (m MockMovieModel) GetAll(title string, genres []string, filters Filters) ([]*Movie, Metadata, error) {
+func (m MockMovieModel) GetAll(movieFilters MovieFilters, filters Filters, fields []string) ([]*Movie, Metadata, error) {
 	return nil, Metadata{}, nil
 }
 
-func (m MockMovieModel) Insert(movie *Movie) error {
+func (m MockMovieModel) Facets(movieFilters MovieFilters, facets []string) (map[string][]FacetCount, error) {
+	return nil, nil
+}
+
+func (m MockMovieModel) Export(ctx context.Context, movieFilters MovieFilters, filters Filters, fields []string, snapshot bool, fn func(movies []*Movie) error) error {
+	return nil
+}
+
+func (m MockMovieModel) Autocomplete(q string, limit int) ([]*MovieSuggestion, error) {
+	return nil, nil
+}
+
+func (m MockMovieModel) Insert(movie *Movie, userID int64) error {
 	return nil
 }
 
@@ -14,10 +31,54 @@ func (m MockMovieModel) Get(id int64) (*Movie, error) {
 	return nil, nil
 }
 
-func (m MockMovieModel) Update(movie *Movie) error {
+func (m MockMovieModel) GetFields(id int64, fields []string) (*Movie, error) {
+	return nil, nil
+}
+
+func (m MockMovieModel) Update(movie *Movie, userID int64) error {
+	return nil
+}
+
+func (m MockMovieModel) Delete(id int64, version int32) error {
+	return nil
+}
+
+func (m MockMovieModel) Batch(ops []*MovieBatchOp, atomic bool, userID int64) error {
+	return nil
+}
+
+func (m MockMovieModel) FindDuplicates(criteria DuplicateCriteria, filters Filters) ([]*DuplicateCandidate, Metadata, error) {
+	return nil, Metadata{}, nil
+}
+
+func (m MockMovieModel) Merge(sourceID, targetID, userID int64) (*Movie, error) {
+	return nil, nil
+}
+
+func (m MockMovieModel) GetFeatures(ctx context.Context) ([]*MovieFeatures, error) {
+	return nil, nil
+}
+
+func (m MockMovieModel) GetUserSignals(userID int64, limit int) ([]*UserSignal, error) {
+	return nil, nil
+}
+
+func (m MockMovieModel) Stats(period string) (*MovieStats, error) {
+	return nil, nil
+}
+
+func (m MockMovieModel) GetTrash(filters Filters) ([]*Movie, Metadata, error) {
+	return nil, Metadata{}, nil
+}
+
+func (m MockMovieModel) Restore(id int64) error {
 	return nil
 }
 
-func (m MockMovieModel) Delete(id int64) error {
+func (m MockMovieModel) Purge(id int64) error {
 	return nil
 }
+
+func (m MockMovieModel) PurgeExpired(deletedBefore time.Time) (int64, error) {
+	return 0, nil
+}
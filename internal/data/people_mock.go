@@ -0,0 +1,23 @@
+package data
+
+type MockPersonModel struct{}
+
+func (m MockPersonModel) GetAll(name string, filters Filters) ([]*Person, Metadata, error) {
+	return nil, Metadata{}, nil
+}
+
+func (m MockPersonModel) Insert(person *Person) error {
+	return nil
+}
+
+func (m MockPersonModel) Get(id int64) (*Person, error) {
+	return nil, nil
+}
+
+func (m MockPersonModel) Update(person *Person) error {
+	return nil
+}
+
+func (m MockPersonModel) Delete(id int64) error {
+	return nil
+}
@@ -0,0 +1,37 @@
+package data
+
+import (
+	"fmt"
+	"strings"
+)
+
+// whereClause collects SQL conditions and their arguments, numbering the
+// placeholders as it goes. Conditions are written with ? in place of each
+// argument, e.g. w.and("year >= ?", 1990), and are always joined with AND.
+type whereClause struct {
+	conditions []string
+	args       []interface{}
+}
+
+func (w *whereClause) and(condition string, args ...interface{}) {
+	for _, arg := range args {
+		condition = strings.Replace(condition, "?", w.param(arg), 1)
+	}
+
+	w.conditions = append(w.conditions, condition)
+}
+
+// param adds a bare argument, for use outside the WHERE clause such as in
+// LIMIT and OFFSET, and returns its placeholder.
+func (w *whereClause) param(arg interface{}) string {
+	w.args = append(w.args, arg)
+	return fmt.Sprintf("$%d", len(w.args))
+}
+
+func (w *whereClause) String() string {
+	if len(w.conditions) == 0 {
+		return ""
+	}
+
+	return "WHERE " + strings.Join(w.conditions, "\n  AND ")
+}
@@ -0,0 +1,109 @@
+package data
+
+import (
+	"context"
+
+	"github.com/lib/pq"
+)
+
+// MovieFeatures is what the recommendation index knows about a movie: enough
+// to compare it with others without loading the whole movie.
+type MovieFeatures struct {
+	ID        int64
+	Year      int32
+	Runtime   int32
+	Genres    []string
+	PersonIDs []int64
+}
+
+// UserSignal is a user's interaction with one movie. Rating is zero when the
+// user has watched the movie without reviewing it.
+type UserSignal struct {
+	MovieID int64
+	Rating  int32
+	Watched bool
+}
+
+// GetFeatures loads the features of every movie outside the trash. It reads
+// the whole catalogue, so it takes a context rather than the usual timeout.
+func (m MovieModel) GetFeatures(ctx context.Context) ([]*MovieFeatures, error) {
+	query := `
+SELECT id, year, runtime, genres, ARRAY(SELECT DISTINCT person_id FROM movie_credits WHERE movie_id = movies.id)
+FROM movies
+WHERE deleted_at IS NULL
+ORDER BY id ASC`
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	features := []*MovieFeatures{}
+
+	for rows.Next() {
+		var f MovieFeatures
+
+		err := rows.Scan(&f.ID, &f.Year, &f.Runtime, pq.Array(&f.Genres), pq.Array(&f.PersonIDs))
+		if err != nil {
+			return nil, err
+		}
+
+		features = append(features, &f)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return features, nil
+}
+
+// GetUserSignals returns the movies the user has rated or watched, most
+// recent activity first, up to limit movies.
+func (m MovieModel) GetUserSignals(userID int64, limit int) ([]*UserSignal, error) {
+	query := `
+SELECT movie_id, COALESCE(MAX(rating), 0), bool_or(watched)
+FROM (
+	SELECT movie_id, rating, false AS watched, created_at AS active_at
+	FROM reviews
+	WHERE user_id = $1
+	UNION ALL
+	SELECT movie_id, NULL::integer, true, watched_at
+	FROM watch_history
+	WHERE user_id = $1
+) AS signals
+GROUP BY movie_id
+ORDER BY MAX(active_at) DESC, movie_id ASC
+LIMIT $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	signals := []*UserSignal{}
+
+	for rows.Next() {
+		var signal UserSignal
+
+		err := rows.Scan(&signal.MovieID, &signal.Rating, &signal.Watched)
+		if err != nil {
+			return nil, err
+		}
+
+		signals = append(signals, &signal)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return signals, nil
+}
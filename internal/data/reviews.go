@@ -0,0 +1,247 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mrityunjaygr8/greenlight/internal/validator"
+)
+
+var ErrDuplicateReview = errors.New("duplicate review")
+
+type Review struct {
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UserID    int64     `json:"user_id"`
+	MovieID   int64     `json:"movie_id"`
+	Rating    int32     `json:"rating"`
+	Text      string    `json:"text"`
+	Version   int32     `json:"version"`
+}
+
+type ReviewModelInterface interface {
+	GetAllForMovie(movieID int64, filters Filters) ([]*Review, Metadata, error)
+	Insert(review *Review) error
+	Get(id int64) (*Review, error)
+	Update(review *Review) error
+	Delete(review *Review) error
+}
+
+type ReviewModel struct {
+	DB *sql.DB
+}
+
+func ValidateReview(v *validator.Validator, review *Review) {
+	v.Check(review.Rating >= 1, "rating", "must be at least 1")
+	v.Check(review.Rating <= 10, "rating", "must not be more than 10")
+
+	v.Check(len(review.Text) <= 10_000, "text", "must not be more than 10000 bytes long")
+}
+
+// lockMovieRating locks the movie's row so that concurrent changes to its
+// reviews are serialized. Without it, two transactions could each recompute
+// the rating from a snapshot missing the other's review. It must be called
+// before the reviews table is touched.
+func lockMovieRating(ctx context.Context, tx *sql.Tx, movieID int64) error {
+	_, err := tx.ExecContext(ctx, `SELECT id FROM movies WHERE id = $1 FOR UPDATE`, movieID)
+	return err
+}
+
+// refreshMovieRating recalculates the denormalized rating columns on the movie
+// from its reviews. It must be called in the same transaction as the change to
+// the reviews table, after lockMovieRating, so the two never drift apart.
+func refreshMovieRating(ctx context.Context, tx *sql.Tx, movieID int64) error {
+	query := `
+UPDATE movies
+SET average_rating = COALESCE((SELECT avg(rating) FROM reviews WHERE movie_id = $1), 0),
+rating_count = (SELECT count(*) FROM reviews WHERE movie_id = $1)
+WHERE id = $1`
+
+	_, err := tx.ExecContext(ctx, query, movieID)
+	return err
+}
+
+func (m ReviewModel) GetAllForMovie(movieID int64, filters Filters) ([]*Review, Metadata, error) {
+	query := fmt.Sprintf(`SELECT count(*) OVER(), id, created_at, user_id, movie_id, rating, text, version
+FROM reviews
+WHERE movie_id = $1
+ORDER BY %s %s, id ASC
+LIMIT $2 OFFSET $3`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, movieID, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	defer rows.Close()
+
+	totalRecords := 0
+	reviews := []*Review{}
+
+	for rows.Next() {
+		var review Review
+
+		err := rows.Scan(&totalRecords, &review.ID, &review.CreatedAt, &review.UserID, &review.MovieID, &review.Rating, &review.Text, &review.Version)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		reviews = append(reviews, &review)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return reviews, metadata, nil
+}
+
+func (m ReviewModel) Insert(review *Review) error {
+	query := `
+INSERT INTO reviews (user_id, movie_id, rating, text)
+VALUES ($1, $2, $3, $4)
+RETURNING id, created_at, version`
+
+	args := []interface{}{review.UserID, review.MovieID, review.Rating, review.Text}
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = lockMovieRating(ctx, tx, review.MovieID)
+	if err != nil {
+		return err
+	}
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&review.ID, &review.CreatedAt, &review.Version)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "reviews_user_movie_key"`:
+			return ErrDuplicateReview
+		default:
+			return err
+		}
+	}
+
+	err = refreshMovieRating(ctx, tx, review.MovieID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (m ReviewModel) Get(id int64) (*Review, error) {
+	query := `SELECT id, created_at, user_id, movie_id, rating, text, version
+FROM reviews
+WHERE id = $1`
+
+	var review Review
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(&review.ID, &review.CreatedAt, &review.UserID, &review.MovieID, &review.Rating, &review.Text, &review.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &review, nil
+}
+
+func (m ReviewModel) Update(review *Review) error {
+	query := `UPDATE reviews
+SET rating = $1, text = $2, version = version + 1
+WHERE id = $3 AND version = $4
+RETURNING version`
+
+	args := []interface{}{review.Rating, review.Text, review.ID, review.Version}
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = lockMovieRating(ctx, tx, review.MovieID)
+	if err != nil {
+		return err
+	}
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&review.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	err = refreshMovieRating(ctx, tx, review.MovieID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (m ReviewModel) Delete(review *Review) error {
+	query := `DELETE FROM reviews WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = lockMovieRating(ctx, tx, review.MovieID)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, query, review.ID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	err = refreshMovieRating(ctx, tx, review.MovieID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
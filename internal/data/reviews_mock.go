@@ -0,0 +1,23 @@
+package data
+
+type MockReviewModel struct{}
+
+func (m MockReviewModel) GetAllForMovie(movieID int64, filters Filters) ([]*Review, Metadata, error) {
+	return nil, Metadata{}, nil
+}
+
+func (m MockReviewModel) Insert(review *Review) error {
+	return nil
+}
+
+func (m MockReviewModel) Get(id int64) (*Review, error) {
+	return nil, nil
+}
+
+func (m MockReviewModel) Update(review *Review) error {
+	return nil
+}
+
+func (m MockReviewModel) Delete(review *Review) error {
+	return nil
+}
@@ -0,0 +1,168 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// MovieSnapshot is the editable state of a movie as of one revision.
+type MovieSnapshot struct {
+	Title   string   `json:"title"`
+	Year    int32    `json:"year"`
+	Runtime Runtime  `json:"runtime"`
+	Genres  []string `json:"genres"`
+}
+
+// FieldChange records the value of a field before and after a revision. From
+// is null for the first revision of a movie.
+type FieldChange struct {
+	From json.RawMessage `json:"from"`
+	To   json.RawMessage `json:"to"`
+}
+
+type MovieRevision struct {
+	MovieID   int64                  `json:"movie_id"`
+	Version   int32                  `json:"version"`
+	CreatedAt time.Time              `json:"created_at"`
+	UserID    int64                  `json:"user_id,omitempty"`
+	Snapshot  MovieSnapshot          `json:"snapshot"`
+	Diff      map[string]FieldChange `json:"diff"`
+}
+
+type RevisionModelInterface interface {
+	GetAllForMovie(movieID int64, filters Filters) ([]*MovieRevision, Metadata, error)
+	Get(movieID int64, version int32) (*MovieRevision, error)
+}
+
+type RevisionModel struct {
+	DB *sql.DB
+}
+
+// recordMovieRevisions snapshots each movie as it stands within tx, diffed
+// against its previous revision. It must run in the same transaction as the
+// write which produced the new versions, so history can never miss an edit. A
+// zero userID records a change made by the system rather than a user.
+//
+// The snapshot and diff are built in SQL so that bulk writes such as imports
+// can record thousands of revisions in one statement. The snapshot must keep
+// the same shape as MovieSnapshot.
+func recordMovieRevisions(ctx context.Context, tx *sql.Tx, movieIDs []int64, userID int64) error {
+	query := `
+INSERT INTO movie_revisions (movie_id, version, user_id, snapshot, diff)
+SELECT movies.id, movies.version, NULLIF($2, 0), current.snapshot, (
+  SELECT COALESCE(jsonb_object_agg(field.key, jsonb_build_object('from', previous.snapshot -> field.key, 'to', field.value)), '{}')
+  FROM jsonb_each(current.snapshot) AS field
+  WHERE previous.snapshot -> field.key IS DISTINCT FROM field.value
+)
+FROM movies
+CROSS JOIN LATERAL (
+  SELECT jsonb_build_object(
+    'title', movies.title,
+    'year', movies.year,
+    'runtime', movies.runtime::text || ' mins',
+    'genres', movies.genres
+  ) AS snapshot
+) AS current
+LEFT JOIN LATERAL (
+  SELECT movie_revisions.snapshot
+  FROM movie_revisions
+  WHERE movie_revisions.movie_id = movies.id AND movie_revisions.version < movies.version
+  ORDER BY movie_revisions.version DESC
+  LIMIT 1
+) AS previous ON true
+WHERE movies.id = ANY($1)`
+
+	_, err := tx.ExecContext(ctx, query, pq.Array(movieIDs), userID)
+	return err
+}
+
+func (m RevisionModel) GetAllForMovie(movieID int64, filters Filters) ([]*MovieRevision, Metadata, error) {
+	query := fmt.Sprintf(`SELECT count(*) OVER(), movie_id, version, created_at, COALESCE(user_id, 0), snapshot, diff
+FROM movie_revisions
+WHERE movie_id = $1
+ORDER BY %s %s
+LIMIT $2 OFFSET $3`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, movieID, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	defer rows.Close()
+
+	totalRecords := 0
+	revisions := []*MovieRevision{}
+
+	for rows.Next() {
+		var revision MovieRevision
+		var snapshot, diff []byte
+
+		err := rows.Scan(&totalRecords, &revision.MovieID, &revision.Version, &revision.CreatedAt, &revision.UserID, &snapshot, &diff)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		err = revision.decode(snapshot, diff)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		revisions = append(revisions, &revision)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return revisions, metadata, nil
+}
+
+func (m RevisionModel) Get(movieID int64, version int32) (*MovieRevision, error) {
+	query := `
+SELECT movie_id, version, created_at, COALESCE(user_id, 0), snapshot, diff
+FROM movie_revisions
+WHERE movie_id = $1 AND version = $2`
+
+	var revision MovieRevision
+	var snapshot, diff []byte
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, movieID, version).Scan(&revision.MovieID, &revision.Version, &revision.CreatedAt, &revision.UserID, &snapshot, &diff)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	err = revision.decode(snapshot, diff)
+	if err != nil {
+		return nil, err
+	}
+
+	return &revision, nil
+}
+
+func (r *MovieRevision) decode(snapshot, diff []byte) error {
+	err := json.Unmarshal(snapshot, &r.Snapshot)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(diff, &r.Diff)
+}
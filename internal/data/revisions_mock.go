@@ -0,0 +1,11 @@
+package data
+
+type MockRevisionModel struct{}
+
+func (m MockRevisionModel) GetAllForMovie(movieID int64, filters Filters) ([]*MovieRevision, Metadata, error) {
+	return nil, Metadata{}, nil
+}
+
+func (m MockRevisionModel) Get(movieID int64, version int32) (*MovieRevision, error) {
+	return nil, nil
+}
@@ -0,0 +1,160 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/mrityunjaygr8/greenlight/internal/validator"
+)
+
+const autocompleteTimeout = 300 * time.Millisecond
+
+// TitleSearchLanguages are the text search configurations a client may pick
+// for title search. The value is interpolated into SQL, so it must only ever
+// come from this list.
+var TitleSearchLanguages = []string{"simple", "english", "french", "german", "italian", "spanish", "portuguese", "dutch"}
+
+type MovieSuggestion struct {
+	ID    int64  `json:"id"`
+	Title string `json:"title"`
+	Year  int32  `json:"year"`
+}
+
+func ValidateAutocomplete(v *validator.Validator, q string, limit int) {
+	v.Check(q != "", "q", "must be provided")
+	v.Check(len(q) <= 100, "q", "must not be more than 100 bytes long")
+
+	v.Check(limit > 0, "limit", "must be greater than 0")
+	v.Check(limit <= 20, "limit", "must be a maximum of 20")
+}
+
+// titlePrefixQuery turns free text into a to_tsquery expression which matches
+// every word, treating the last one as a prefix so partially typed titles
+// still match. Only letters and digits survive, so the result is always a
+// syntactically valid tsquery.
+func titlePrefixQuery(q string) string {
+	words := strings.FieldsFunc(strings.ToLower(q), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	if len(words) == 0 {
+		return ""
+	}
+
+	words[len(words)-1] += ":*"
+
+	return strings.Join(words, " & ")
+}
+
+func (f MovieFilters) language() string {
+	if f.Language == "" {
+		return "simple"
+	}
+
+	for _, language := range TitleSearchLanguages {
+		if f.Language == language {
+			return language
+		}
+	}
+
+	panic("unsafe title search language: " + f.Language)
+}
+
+// titleCondition matches the original title or any translated title against
+// the title search.
+func (f MovieFilters) titleCondition(w *whereClause) {
+	original, args := f.titleMatch("title")
+	translated, translatedArgs := f.titleMatch("movie_translations.title")
+
+	w.and(fmt.Sprintf("(%s OR id IN (SELECT movie_id FROM movie_translations WHERE %s))", original, translated), append(args, translatedArgs...)...)
+}
+
+// titleMatch returns a condition matching column as full text with a trailing
+// prefix and, when fuzzy matching is on, by trigram similarity to catch
+// misspellings, along with its arguments.
+func (f MovieFilters) titleMatch(column string) (string, []interface{}) {
+	language := f.language()
+	tsquery := titlePrefixQuery(f.Title)
+
+	fullText := fmt.Sprintf("to_tsvector('%s', %s) @@ to_tsquery('%s', ?)", language, column, language)
+	similar := column + " % ?"
+
+	switch {
+	case f.Fuzzy && tsquery != "":
+		return "(" + fullText + " OR " + similar + ")", []interface{}{tsquery, f.Title}
+	case f.Fuzzy:
+		return similar, []interface{}{f.Title}
+	default:
+		return fullText, []interface{}{tsquery}
+	}
+}
+
+// relevance returns an SQL expression scoring how well each movie matches the
+// title search, adding its arguments to w. A movie scores as well as the best
+// matching of its original and translated titles.
+func (f MovieFilters) relevance(w *whereClause) string {
+	original := f.titleRank(w, "title")
+	translated := f.titleRank(w, "movie_translations.title")
+
+	return fmt.Sprintf(`GREATEST(%s, COALESCE((
+    SELECT max(%s) FROM movie_translations WHERE movie_translations.movie_id = movies.id
+  ), 0))`, original, translated)
+}
+
+func (f MovieFilters) titleRank(w *whereClause, column string) string {
+	language := f.language()
+
+	rank := fmt.Sprintf("ts_rank(to_tsvector('%s', %s), to_tsquery('%s', %s))", language, column, language, w.param(titlePrefixQuery(f.Title)))
+
+	if !f.Fuzzy {
+		return rank
+	}
+
+	return fmt.Sprintf("(%s + similarity(%s, %s))", rank, column, w.param(f.Title))
+}
+
+// Autocomplete returns up to limit titles starting with, or closely resembling,
+// q. It runs under a much tighter deadline than other queries since it is
+// called on every keystroke.
+func (m MovieModel) Autocomplete(q string, limit int) ([]*MovieSuggestion, error) {
+	escaper := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+	query := `
+SELECT id, title, year
+FROM movies
+WHERE deleted_at IS NULL AND (title ILIKE $1 || '%' OR title % $2)
+ORDER BY title ILIKE $1 || '%' DESC, similarity(title, $2) DESC, rating_count DESC, id ASC
+LIMIT $3`
+
+	ctx, cancel := context.WithTimeout(context.Background(), autocompleteTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, escaper.Replace(q), q, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	suggestions := []*MovieSuggestion{}
+
+	for rows.Next() {
+		var suggestion MovieSuggestion
+
+		err := rows.Scan(&suggestion.ID, &suggestion.Title, &suggestion.Year)
+		if err != nil {
+			return nil, err
+		}
+
+		suggestions = append(suggestions, &suggestion)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return suggestions, nil
+}
@@ -0,0 +1,175 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/mrityunjaygr8/greenlight/internal/validator"
+)
+
+// StatsPeriodSafelist lists the periods catalogue growth can be grouped by.
+var StatsPeriodSafelist = []string{"day", "week", "month", "year"}
+
+// statsTimeout bounds computing the catalogue statistics, which read every
+// movie several times over.
+const statsTimeout = 10 * time.Second
+
+// MovieStats summarises the movies outside the trash.
+type MovieStats struct {
+	Total          int            `json:"total"`
+	Genres         []*GenreStats  `json:"genres"`
+	Decades        []FacetCount   `json:"decades"`
+	RuntimeBuckets []FacetCount   `json:"runtime_buckets"`
+	Growth         []*GrowthPoint `json:"growth"`
+	GeneratedAt    time.Time      `json:"generated_at"`
+}
+
+// GenreStats counts the movies in a genre. The average runtime leaves out
+// movies whose runtime is unknown, and is zero if all of them are.
+type GenreStats struct {
+	Genre          string  `json:"genre"`
+	Count          int     `json:"count"`
+	AverageRuntime float64 `json:"average_runtime"`
+}
+
+// GrowthPoint is how many movies were added in the period starting on
+// Period, and how many had been added by its end. Periods in which no movies
+// were added are left out.
+type GrowthPoint struct {
+	Period string `json:"period"`
+	Added  int    `json:"added"`
+	Total  int    `json:"total"`
+}
+
+func ValidateStatsPeriod(v *validator.Validator, period string) {
+	v.Check(validator.In(period, StatsPeriodSafelist...), "period", "must be one of day, week, month or year")
+}
+
+// Stats computes statistics over the whole catalogue, with growth grouped by
+// period. The decade and runtime buckets are those of the listing facets. The
+// queries share one repeatable read transaction, so every figure comes from
+// the same snapshot and the totals agree with each other.
+func (m MovieModel) Stats(period string) (*MovieStats, error) {
+	if !validator.In(period, StatsPeriodSafelist...) {
+		panic("unsafe stats period: " + period)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), statsTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	stats := &MovieStats{GeneratedAt: time.Now()}
+
+	err = tx.QueryRowContext(ctx, `SELECT count(*) FROM movies WHERE deleted_at IS NULL`).Scan(&stats.Total)
+	if err != nil {
+		return nil, err
+	}
+
+	stats.Genres, err = genreStats(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	stats.Decades, err = facetCounts(ctx, tx, MovieFilters{}, FacetDecade)
+	if err != nil {
+		return nil, err
+	}
+
+	stats.RuntimeBuckets, err = facetCounts(ctx, tx, MovieFilters{}, FacetRuntimeBucket)
+	if err != nil {
+		return nil, err
+	}
+
+	stats.Growth, err = growth(ctx, tx, period)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+func genreStats(ctx context.Context, tx *sql.Tx) ([]*GenreStats, error) {
+	query := `
+SELECT genre, count(*), COALESCE(round(avg(runtime) FILTER (WHERE runtime > 0), 1), 0)::float8
+FROM movies
+CROSS JOIN unnest(movies.genres) AS genre
+WHERE deleted_at IS NULL
+GROUP BY genre
+ORDER BY count(*) DESC, genre ASC`
+
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	genres := []*GenreStats{}
+
+	for rows.Next() {
+		var genre GenreStats
+
+		err := rows.Scan(&genre.Genre, &genre.Count, &genre.AverageRuntime)
+		if err != nil {
+			return nil, err
+		}
+
+		genres = append(genres, &genre)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return genres, nil
+}
+
+// growth groups movies by the UTC period their created_at falls in.
+func growth(ctx context.Context, tx *sql.Tx, period string) ([]*GrowthPoint, error) {
+	query := `
+SELECT to_char(period, 'YYYY-MM-DD'), added, sum(added) OVER (ORDER BY period)::bigint
+FROM (
+	SELECT date_trunc($1, created_at AT TIME ZONE 'UTC') AS period, count(*) AS added
+	FROM movies
+	WHERE deleted_at IS NULL
+	GROUP BY 1
+) AS periods
+ORDER BY period ASC`
+
+	rows, err := tx.QueryContext(ctx, query, period)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	points := []*GrowthPoint{}
+
+	for rows.Next() {
+		var point GrowthPoint
+
+		err := rows.Scan(&point.Period, &point.Added, &point.Total)
+		if err != nil {
+			return nil, err
+		}
+
+		points = append(points, &point)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return points, nil
+}
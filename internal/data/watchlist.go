@@ -0,0 +1,184 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type WatchlistEntry struct {
+	AddedAt time.Time `json:"added_at"`
+	Movie   *Movie    `json:"movie"`
+}
+
+type WatchlistModelInterface interface {
+	GetAll(userID int64, filters Filters) ([]*WatchlistEntry, Metadata, error)
+	Get(userID, movieID int64) (*WatchlistEntry, error)
+	Put(userID, movieID int64) (*WatchlistEntry, error)
+	Delete(userID, movieID int64) error
+	Includes(userID int64, movieIDs ...int64) (map[int64]bool, error)
+}
+
+type WatchlistModel struct {
+	DB *sql.DB
+}
+
+func (m WatchlistModel) GetAll(userID int64, filters Filters) ([]*WatchlistEntry, Metadata, error) {
+	query := fmt.Sprintf(`SELECT count(*) OVER(), watchlist_entries.added_at, movies.id, movies.title, movies.year, movies.runtime, movies.genres, movies.version
+FROM watchlist_entries
+INNER JOIN movies ON movies.id = watchlist_entries.movie_id
+WHERE watchlist_entries.user_id = $1 AND movies.deleted_at IS NULL
+ORDER BY %s %s, movies.id ASC
+LIMIT $2 OFFSET $3`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	defer rows.Close()
+
+	totalRecords := 0
+	entries := []*WatchlistEntry{}
+
+	for rows.Next() {
+		entry := WatchlistEntry{Movie: &Movie{}}
+
+		err := rows.Scan(&totalRecords, &entry.AddedAt, &entry.Movie.ID, &entry.Movie.Title, &entry.Movie.Year, &entry.Movie.Runtime, pq.Array(&entry.Movie.Genres), &entry.Movie.Version)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		entries = append(entries, &entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return entries, metadata, nil
+}
+
+func (m WatchlistModel) Get(userID, movieID int64) (*WatchlistEntry, error) {
+	query := `SELECT watchlist_entries.added_at, movies.id, movies.title, movies.year, movies.runtime, movies.genres, movies.version
+FROM watchlist_entries
+INNER JOIN movies ON movies.id = watchlist_entries.movie_id
+WHERE watchlist_entries.user_id = $1 AND watchlist_entries.movie_id = $2 AND movies.deleted_at IS NULL`
+
+	entry := WatchlistEntry{Movie: &Movie{}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, userID, movieID).Scan(&entry.AddedAt, &entry.Movie.ID, &entry.Movie.Title, &entry.Movie.Year, &entry.Movie.Runtime, pq.Array(&entry.Movie.Genres), &entry.Movie.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &entry, nil
+}
+
+// Put adds the movie to the user's watchlist. Adding a movie which is already
+// on the watchlist is not an error, and keeps the original added_at time.
+// Movies in the trash cannot be added.
+func (m WatchlistModel) Put(userID, movieID int64) (*WatchlistEntry, error) {
+	query := `
+INSERT INTO watchlist_entries (user_id, movie_id)
+SELECT $1, id
+FROM movies
+WHERE id = $2 AND deleted_at IS NULL
+ON CONFLICT (user_id, movie_id) DO UPDATE SET added_at = watchlist_entries.added_at`
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, userID, movieID)
+	if err != nil {
+		return nil, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	if rowsAffected == 0 {
+		return nil, ErrRecordNotFound
+	}
+
+	return m.Get(userID, movieID)
+}
+
+func (m WatchlistModel) Delete(userID, movieID int64) error {
+	query := `DELETE FROM watchlist_entries WHERE user_id = $1 AND movie_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, userID, movieID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (m WatchlistModel) Includes(userID int64, movieIDs ...int64) (map[int64]bool, error) {
+	included := make(map[int64]bool, len(movieIDs))
+
+	if len(movieIDs) == 0 {
+		return included, nil
+	}
+
+	query := `SELECT movie_id FROM watchlist_entries WHERE user_id = $1 AND movie_id = ANY($2)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID, pq.Array(movieIDs))
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var movieID int64
+
+		err := rows.Scan(&movieID)
+		if err != nil {
+			return nil, err
+		}
+
+		included[movieID] = true
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return included, nil
+}
@@ -0,0 +1,23 @@
+package data
+
+type MockWatchlistModel struct{}
+
+func (m MockWatchlistModel) GetAll(userID int64, filters Filters) ([]*WatchlistEntry, Metadata, error) {
+	return nil, Metadata{}, nil
+}
+
+func (m MockWatchlistModel) Get(userID, movieID int64) (*WatchlistEntry, error) {
+	return nil, nil
+}
+
+func (m MockWatchlistModel) Put(userID, movieID int64) (*WatchlistEntry, error) {
+	return nil, nil
+}
+
+func (m MockWatchlistModel) Delete(userID, movieID int64) error {
+	return nil
+}
+
+func (m MockWatchlistModel) Includes(userID int64, movieIDs ...int64) (map[int64]bool, error) {
+	return nil, nil
+}
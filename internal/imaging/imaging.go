@@ -0,0 +1,141 @@
+// Package imaging decodes, validates and resizes uploaded images.
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+
+	"golang.org/x/image/draw"
+)
+
+var ErrUnsupportedFormat = errors.New("unsupported image format")
+
+// ContentTypes lists the image types which can be decoded.
+var ContentTypes = []string{"image/jpeg", "image/png"}
+
+// jpegQuality is the quality images are re-encoded at. High enough that the
+// round trip is not visible, which matters since every upload is re-encoded.
+const jpegQuality = 90
+
+// Image is a decoded image along with the type it was uploaded as.
+type Image struct {
+	image.Image
+	ContentType string
+}
+
+// DetectContentType sniffs the type of an image from its leading bytes,
+// returning ErrUnsupportedFormat for anything but JPEG and PNG.
+func DetectContentType(data []byte) (string, error) {
+	contentType := http.DetectContentType(data)
+
+	for _, supported := range ContentTypes {
+		if contentType == supported {
+			return contentType, nil
+		}
+	}
+
+	return "", ErrUnsupportedFormat
+}
+
+// DecodeConfig reads just the dimensions of an image, so that oversized images
+// can be turned away before they are decoded in full. For JPEGs rotated by
+// their EXIF orientation, the dimensions are those after rotation.
+func DecodeConfig(data []byte) (width, height int, err error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if orientation(data) >= 5 {
+		return config.Height, config.Width, nil
+	}
+
+	return config.Width, config.Height, nil
+}
+
+// Decode decodes a JPEG or PNG, turning JPEGs upright according to their EXIF
+// orientation. Metadata is not carried over into the decoded image, so
+// encoding it again strips EXIF data such as camera details and location.
+func Decode(data []byte) (*Image, error) {
+	contentType, err := DetectContentType(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var img image.Image
+
+	switch contentType {
+	case "image/jpeg":
+		img, err = jpeg.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+
+		img = orient(img, orientation(data))
+	default:
+		img, err = png.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &Image{Image: img, ContentType: contentType}, nil
+}
+
+// Fit scales the image down to fit within maxWidth by maxHeight, keeping its
+// aspect ratio. Images which already fit are returned as they are.
+func (img *Image) Fit(maxWidth, maxHeight int) *Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	if width <= maxWidth && height <= maxHeight {
+		return img
+	}
+
+	// Compare the aspect ratios by cross-multiplying to stay in integers.
+	if width*maxHeight > height*maxWidth {
+		height = max(1, height*maxWidth/width)
+		width = maxWidth
+	} else {
+		width = max(1, width*maxHeight/height)
+		height = maxHeight
+	}
+
+	resized := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(resized, resized.Bounds(), img.Image, bounds, draw.Src, nil)
+
+	return &Image{Image: resized, ContentType: img.ContentType}
+}
+
+// Encode writes the image in the format it was uploaded in.
+func (img *Image) Encode(w io.Writer) error {
+	switch img.ContentType {
+	case "image/jpeg":
+		return jpeg.Encode(w, img.Image, &jpeg.Options{Quality: jpegQuality})
+	case "image/png":
+		return png.Encode(w, img.Image)
+	}
+
+	return ErrUnsupportedFormat
+}
+
+// Extension returns the file extension for the image's format.
+func (img *Image) Extension() string {
+	if img.ContentType == "image/png" {
+		return ".png"
+	}
+
+	return ".jpg"
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,424 @@
+// Package jsonpatch applies JSON Patch (RFC 6902) and JSON Merge Patch
+// (RFC 7396) documents to JSON values.
+package jsonpatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidPatch = errors.New("invalid patch")
+	ErrTestFailed   = errors.New("test operation failed")
+)
+
+// Operation is a single JSON Patch operation. From is only used by move and
+// copy, and Value only by add, replace and test.
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// Patch is a JSON Patch document. Its operations are applied in order, and if
+// any of them fails the document is left untouched.
+type Patch []Operation
+
+// Apply returns doc with every operation of the patch applied.
+func (p Patch) Apply(doc []byte) ([]byte, error) {
+	root, err := decode(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, op := range p {
+		root, err = op.apply(root)
+		if err != nil {
+			return nil, fmt.Errorf("operation %d (%s %s): %w", i, op.Op, op.Path, err)
+		}
+	}
+
+	return json.Marshal(root)
+}
+
+func (op Operation) apply(root interface{}) (interface{}, error) {
+	switch op.Op {
+	case "add", "replace", "test":
+		if op.Value == nil {
+			return nil, fmt.Errorf("%w: missing value", ErrInvalidPatch)
+		}
+
+		value, err := decode(op.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		switch op.Op {
+		case "add":
+			return add(root, op.Path, value)
+		case "replace":
+			root, _, err = remove(root, op.Path)
+			if err != nil {
+				return nil, err
+			}
+			return add(root, op.Path, value)
+		default:
+			current, err := get(root, op.Path)
+			if err != nil {
+				return nil, err
+			}
+			if !equal(current, value) {
+				return nil, ErrTestFailed
+			}
+			return root, nil
+		}
+
+	case "remove":
+		root, _, err := remove(root, op.Path)
+		return root, err
+
+	case "move":
+		if op.Path == op.From || strings.HasPrefix(op.Path, op.From+"/") {
+			return nil, fmt.Errorf("%w: cannot move a value into itself", ErrInvalidPatch)
+		}
+
+		root, value, err := remove(root, op.From)
+		if err != nil {
+			return nil, err
+		}
+		return add(root, op.Path, value)
+
+	case "copy":
+		value, err := get(root, op.From)
+		if err != nil {
+			return nil, err
+		}
+
+		// The value is round-tripped so the copy shares nothing with the original.
+		js, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		value, err = decode(js)
+		if err != nil {
+			return nil, err
+		}
+		return add(root, op.Path, value)
+	}
+
+	return nil, fmt.Errorf("%w: unknown operation %q", ErrInvalidPatch, op.Op)
+}
+
+// MergePatch returns doc with the merge patch applied: objects are merged key
+// by key, null removes a key and any other value replaces the target outright.
+func MergePatch(doc, patch []byte) ([]byte, error) {
+	target, err := decode(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	merge, err := decode(patch)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(mergeValues(target, merge))
+}
+
+func mergeValues(target, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = map[string]interface{}{}
+	}
+
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+			continue
+		}
+
+		targetObject[key] = mergeValues(targetObject[key], value)
+	}
+
+	return targetObject
+}
+
+// equal compares two decoded values as RFC 6902 requires for test: numbers by
+// value, so 1 and 1.0 are equal, objects regardless of key order and arrays
+// element by element.
+func equal(a, b interface{}) bool {
+	switch a := a.(type) {
+	case json.Number:
+		b, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+
+		x, okX := new(big.Rat).SetString(a.String())
+		y, okY := new(big.Rat).SetString(b.String())
+		if !okX || !okY {
+			return a == b
+		}
+		return x.Cmp(y) == 0
+
+	case map[string]interface{}:
+		b, ok := b.(map[string]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+
+		for key, value := range a {
+			other, ok := b[key]
+			if !ok || !equal(value, other) {
+				return false
+			}
+		}
+		return true
+
+	case []interface{}:
+		b, ok := b.([]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+
+		for i := range a {
+			if !equal(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	}
+
+	return a == b
+}
+
+func decode(js []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(js))
+	dec.UseNumber()
+
+	var value interface{}
+
+	err := dec.Decode(&value)
+	if err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+// parsePointer splits a JSON Pointer (RFC 6901) into its unescaped tokens.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("%w: path %q must start with /", ErrInvalidPatch, pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+	}
+
+	return tokens, nil
+}
+
+// arrayIndex parses an array index token. "-" is only allowed when adding, in
+// which case it refers to the position after the last element.
+func arrayIndex(token string, length int, adding bool) (int, error) {
+	if adding && token == "-" {
+		return length, nil
+	}
+
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || (token != "0" && strings.HasPrefix(token, "0")) {
+		return 0, fmt.Errorf("%w: invalid array index %q", ErrInvalidPatch, token)
+	}
+
+	limit := length
+	if adding {
+		limit++
+	}
+
+	if index >= limit {
+		return 0, fmt.Errorf("%w: array index %d out of range", ErrInvalidPatch, index)
+	}
+
+	return index, nil
+}
+
+func get(root interface{}, pointer string) (interface{}, error) {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+
+	value := root
+
+	for _, token := range tokens {
+		switch container := value.(type) {
+		case map[string]interface{}:
+			child, ok := container[token]
+			if !ok {
+				return nil, fmt.Errorf("%w: path %q does not exist", ErrInvalidPatch, pointer)
+			}
+			value = child
+		case []interface{}:
+			index, err := arrayIndex(token, len(container), false)
+			if err != nil {
+				return nil, err
+			}
+			value = container[index]
+		default:
+			return nil, fmt.Errorf("%w: path %q does not exist", ErrInvalidPatch, pointer)
+		}
+	}
+
+	return value, nil
+}
+
+// parent resolves everything but the last token of the pointer, returning the
+// containing value and that last token.
+func parent(root interface{}, pointer string) (interface{}, string, error) {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if len(tokens) == 0 {
+		return nil, "", nil
+	}
+
+	last := len(tokens) - 1
+
+	container, err := get(root, pointerOf(tokens[:last]))
+	if err != nil {
+		return nil, "", err
+	}
+
+	return container, tokens[last], nil
+}
+
+func pointerOf(tokens []string) string {
+	var b strings.Builder
+
+	for _, token := range tokens {
+		b.WriteString("/")
+		b.WriteString(strings.NewReplacer("~", "~0", "/", "~1").Replace(token))
+	}
+
+	return b.String()
+}
+
+// add sets the value at pointer, inserting into arrays rather than
+// overwriting, and returns the new root.
+func add(root interface{}, pointer string, value interface{}) (interface{}, error) {
+	if pointer == "" {
+		return value, nil
+	}
+
+	container, token, err := parent(root, pointer)
+	if err != nil {
+		return nil, err
+	}
+
+	switch container := container.(type) {
+	case map[string]interface{}:
+		container[token] = value
+		return root, nil
+	case []interface{}:
+		index, err := arrayIndex(token, len(container), true)
+		if err != nil {
+			return nil, err
+		}
+
+		updated := append(container[:index:index], value)
+		updated = append(updated, container[index:]...)
+
+		return replaceAt(root, pointer, updated)
+	}
+
+	return nil, fmt.Errorf("%w: path %q does not exist", ErrInvalidPatch, pointer)
+}
+
+// remove deletes the value at pointer, returning the new root and the removed
+// value.
+func remove(root interface{}, pointer string) (interface{}, interface{}, error) {
+	if pointer == "" {
+		return nil, root, nil
+	}
+
+	container, token, err := parent(root, pointer)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch container := container.(type) {
+	case map[string]interface{}:
+		value, ok := container[token]
+		if !ok {
+			return nil, nil, fmt.Errorf("%w: path %q does not exist", ErrInvalidPatch, pointer)
+		}
+
+		delete(container, token)
+		return root, value, nil
+	case []interface{}:
+		index, err := arrayIndex(token, len(container), false)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		value := container[index]
+		updated := append(container[:index:index], container[index+1:]...)
+
+		root, err = replaceAt(root, pointer, updated)
+		return root, value, err
+	}
+
+	return nil, nil, fmt.Errorf("%w: path %q does not exist", ErrInvalidPatch, pointer)
+}
+
+// replaceAt swaps the array holding the element at pointer for updated. Arrays
+// change length on insert and removal, so unlike objects they cannot be
+// modified in place.
+func replaceAt(root interface{}, pointer string, updated []interface{}) (interface{}, error) {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+
+	arrayPointer := pointerOf(tokens[:len(tokens)-1])
+	if arrayPointer == "" {
+		return updated, nil
+	}
+
+	container, token, err := parent(root, arrayPointer)
+	if err != nil {
+		return nil, err
+	}
+
+	switch container := container.(type) {
+	case map[string]interface{}:
+		container[token] = updated
+	case []interface{}:
+		index, err := arrayIndex(token, len(container), false)
+		if err != nil {
+			return nil, err
+		}
+		container[index] = updated
+	}
+
+	return root, nil
+}
@@ -0,0 +1,288 @@
+package jsonpatch
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestPatchApply(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+		want  string
+		err   error
+	}{
+		{
+			name:  "add object member",
+			doc:   `{"a":1}`,
+			patch: `[{"op":"add","path":"/b","value":2}]`,
+			want:  `{"a":1,"b":2}`,
+		},
+		{
+			name:  "add replaces existing member",
+			doc:   `{"a":1}`,
+			patch: `[{"op":"add","path":"/a","value":[1,2]}]`,
+			want:  `{"a":[1,2]}`,
+		},
+		{
+			name:  "add inserts into array",
+			doc:   `{"a":[1,3]}`,
+			patch: `[{"op":"add","path":"/a/1","value":2}]`,
+			want:  `{"a":[1,2,3]}`,
+		},
+		{
+			name:  "add appends with dash",
+			doc:   `{"a":[1,2]}`,
+			patch: `[{"op":"add","path":"/a/-","value":3}]`,
+			want:  `{"a":[1,2,3]}`,
+		},
+		{
+			name:  "add appends to nested array with dash",
+			doc:   `{"a":[{"b":[]}]}`,
+			patch: `[{"op":"add","path":"/a/0/b/-","value":"x"}]`,
+			want:  `{"a":[{"b":["x"]}]}`,
+		},
+		{
+			name:  "add replaces whole document",
+			doc:   `{"a":1}`,
+			patch: `[{"op":"add","path":"","value":[1]}]`,
+			want:  `[1]`,
+		},
+		{
+			name:  "add past end of array",
+			doc:   `{"a":[1]}`,
+			patch: `[{"op":"add","path":"/a/2","value":2}]`,
+			err:   ErrInvalidPatch,
+		},
+		{
+			name:  "add to missing parent",
+			doc:   `{}`,
+			patch: `[{"op":"add","path":"/a/b","value":1}]`,
+			err:   ErrInvalidPatch,
+		},
+		{
+			name:  "add without value",
+			doc:   `{}`,
+			patch: `[{"op":"add","path":"/a"}]`,
+			err:   ErrInvalidPatch,
+		},
+		{
+			name:  "pointer escapes slash",
+			doc:   `{"a/b":1}`,
+			patch: `[{"op":"replace","path":"/a~1b","value":2}]`,
+			want:  `{"a/b":2}`,
+		},
+		{
+			name:  "pointer escapes tilde",
+			doc:   `{"a~b":1}`,
+			patch: `[{"op":"remove","path":"/a~0b"}]`,
+			want:  `{}`,
+		},
+		{
+			name:  "pointer unescapes tilde before slash",
+			doc:   `{"~1":1}`,
+			patch: `[{"op":"remove","path":"/~01"}]`,
+			want:  `{}`,
+		},
+		{
+			name:  "pointer without leading slash",
+			doc:   `{"a":1}`,
+			patch: `[{"op":"remove","path":"a"}]`,
+			err:   ErrInvalidPatch,
+		},
+		{
+			name:  "remove object member",
+			doc:   `{"a":1,"b":2}`,
+			patch: `[{"op":"remove","path":"/a"}]`,
+			want:  `{"b":2}`,
+		},
+		{
+			name:  "remove array element",
+			doc:   `{"a":[1,2,3]}`,
+			patch: `[{"op":"remove","path":"/a/1"}]`,
+			want:  `{"a":[1,3]}`,
+		},
+		{
+			name:  "remove missing member",
+			doc:   `{"a":1}`,
+			patch: `[{"op":"remove","path":"/b"}]`,
+			err:   ErrInvalidPatch,
+		},
+		{
+			name:  "remove with dash",
+			doc:   `{"a":[1]}`,
+			patch: `[{"op":"remove","path":"/a/-"}]`,
+			err:   ErrInvalidPatch,
+		},
+		{
+			name:  "remove array index with leading zero",
+			doc:   `{"a":[1,2]}`,
+			patch: `[{"op":"remove","path":"/a/01"}]`,
+			err:   ErrInvalidPatch,
+		},
+		{
+			name:  "replace missing member",
+			doc:   `{}`,
+			patch: `[{"op":"replace","path":"/a","value":1}]`,
+			err:   ErrInvalidPatch,
+		},
+		{
+			name:  "move member",
+			doc:   `{"a":1,"b":{}}`,
+			patch: `[{"op":"move","from":"/a","path":"/b/c"}]`,
+			want:  `{"b":{"c":1}}`,
+		},
+		{
+			name:  "move array element",
+			doc:   `{"a":[1,2,3]}`,
+			patch: `[{"op":"move","from":"/a/0","path":"/a/-"}]`,
+			want:  `{"a":[2,3,1]}`,
+		},
+		{
+			name:  "move into itself",
+			doc:   `{"a":{"b":1}}`,
+			patch: `[{"op":"move","from":"/a","path":"/a/b"}]`,
+			err:   ErrInvalidPatch,
+		},
+		{
+			name:  "move from missing path",
+			doc:   `{}`,
+			patch: `[{"op":"move","from":"/a","path":"/b"}]`,
+			err:   ErrInvalidPatch,
+		},
+		{
+			name:  "copy member",
+			doc:   `{"a":{"b":1}}`,
+			patch: `[{"op":"copy","from":"/a","path":"/c"},{"op":"replace","path":"/c/b","value":2}]`,
+			want:  `{"a":{"b":1},"c":{"b":2}}`,
+		},
+		{
+			name:  "copy from missing path",
+			doc:   `{}`,
+			patch: `[{"op":"copy","from":"/a","path":"/b"}]`,
+			err:   ErrInvalidPatch,
+		},
+		{
+			name:  "test passes",
+			doc:   `{"a":{"b":[1,"x",true,null]}}`,
+			patch: `[{"op":"test","path":"/a","value":{"b":[1,"x",true,null]}}]`,
+			want:  `{"a":{"b":[1,"x",true,null]}}`,
+		},
+		{
+			name:  "test compares numbers by value",
+			doc:   `{"a":1,"b":[100]}`,
+			patch: `[{"op":"test","path":"/a","value":1.0},{"op":"test","path":"/b/0","value":1e2}]`,
+			want:  `{"a":1,"b":[100]}`,
+		},
+		{
+			name:  "test fails on different value",
+			doc:   `{"a":1}`,
+			patch: `[{"op":"test","path":"/a","value":2}]`,
+			err:   ErrTestFailed,
+		},
+		{
+			name:  "test fails on different type",
+			doc:   `{"a":1}`,
+			patch: `[{"op":"test","path":"/a","value":"1"}]`,
+			err:   ErrTestFailed,
+		},
+		{
+			name:  "test fails on extra member",
+			doc:   `{"a":{"b":1}}`,
+			patch: `[{"op":"test","path":"/a","value":{"b":1,"c":2}}]`,
+			err:   ErrTestFailed,
+		},
+		{
+			name:  "test on missing path",
+			doc:   `{}`,
+			patch: `[{"op":"test","path":"/a","value":1}]`,
+			err:   ErrInvalidPatch,
+		},
+		{
+			name:  "unknown operation",
+			doc:   `{}`,
+			patch: `[{"op":"frobnicate","path":"/a"}]`,
+			err:   ErrInvalidPatch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var patch Patch
+
+			err := json.Unmarshal([]byte(tt.patch), &patch)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := patch.Apply([]byte(tt.doc))
+
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("got error %v; want %v", err, tt.err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(got) != tt.want {
+				t.Errorf("got %s; want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPatchApplyLeavesDocumentOnFailure(t *testing.T) {
+	doc := []byte(`{"a":[1,2]}`)
+
+	var patch Patch
+
+	err := json.Unmarshal([]byte(`[{"op":"remove","path":"/a/0"},{"op":"test","path":"/a/0","value":1}]`), &patch)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = patch.Apply(doc)
+	if !errors.Is(err, ErrTestFailed) {
+		t.Fatalf("got error %v; want %v", err, ErrTestFailed)
+	}
+
+	if string(doc) != `{"a":[1,2]}` {
+		t.Errorf("document changed to %s", doc)
+	}
+}
+
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+		want  string
+	}{
+		{"replace member", `{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{"add member", `{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{"null removes member", `{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{"arrays are replaced", `{"a":[1,2]}`, `{"a":[3]}`, `{"a":[3]}`},
+		{"nested objects are merged", `{"a":{"b":1,"c":2}}`, `{"a":{"c":null,"d":3}}`, `{"a":{"b":1,"d":3}}`},
+		{"non-object patch replaces document", `{"a":1}`, `[1]`, `[1]`},
+		{"object patch onto non-object", `[1]`, `{"a":1}`, `{"a":1}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MergePatch([]byte(tt.doc), []byte(tt.patch))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(got) != tt.want {
+				t.Errorf("got %s; want %s", got, tt.want)
+			}
+		})
+	}
+}
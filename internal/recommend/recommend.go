@@ -0,0 +1,233 @@
+// Package recommend ranks movies by how alike they are, from an in-memory
+// index of their genres, release years, runtimes and credits.
+package recommend
+
+import (
+	"math"
+	"sort"
+
+	"github.com/mrityunjaygr8/greenlight/internal/data"
+)
+
+// The weights of each kind of likeness in a similarity score. They add up to
+// one, so scores range from 0 to 1.
+const (
+	genreWeight   = 0.4
+	creditWeight  = 0.35
+	eraWeight     = 0.15
+	runtimeWeight = 0.1
+)
+
+const (
+	// eraSpan is how many years apart two movies can be before their era
+	// stops counting towards their likeness.
+	eraSpan = 20
+	// runtimeSpan is the same for runtimes, in minutes.
+	runtimeSpan = 60
+	// creditSaturation is how many people two movies must share for credits
+	// to count in full. A couple of shared leads matter more than the
+	// fraction of a large crew they make up.
+	creditSaturation = 3
+)
+
+// watchedWeight is how much a watched but unrated movie counts towards a
+// user's taste, on the scale where a 10/10 rating counts 1.
+const watchedWeight = 0.5
+
+// Match is a movie found by the index along with its score.
+type Match struct {
+	MovieID int64
+	Score   float64
+}
+
+// Index holds the features of every movie along with inverted indexes from
+// genres and people to the movies they appear in. Only movies sharing a genre
+// or a person with the one being compared are scored, which keeps lookups
+// well short of a scan of the whole catalogue. An Index is never modified
+// once built, so it is safe for concurrent use.
+type Index struct {
+	movies   []*data.MovieFeatures
+	byID     map[int64]int
+	byGenre  map[string][]int
+	byPerson map[int64][]int
+}
+
+// New builds an index over the given movies.
+func New(movies []*data.MovieFeatures) *Index {
+	idx := &Index{
+		movies:   movies,
+		byID:     make(map[int64]int, len(movies)),
+		byGenre:  make(map[string][]int),
+		byPerson: make(map[int64][]int),
+	}
+
+	for i, movie := range movies {
+		idx.byID[movie.ID] = i
+
+		for _, genre := range movie.Genres {
+			idx.byGenre[genre] = append(idx.byGenre[genre], i)
+		}
+
+		for _, person := range movie.PersonIDs {
+			idx.byPerson[person] = append(idx.byPerson[person], i)
+		}
+	}
+
+	return idx
+}
+
+// Len reports the number of movies in the index.
+func (idx *Index) Len() int {
+	return len(idx.movies)
+}
+
+// Has reports whether the movie is in the index.
+func (idx *Index) Has(movieID int64) bool {
+	_, ok := idx.byID[movieID]
+	return ok
+}
+
+// Similar returns up to limit movies most like the given one, best first.
+// Movies the index does not know have no matches.
+func (idx *Index) Similar(movieID int64, limit int) []Match {
+	i, ok := idx.byID[movieID]
+	if !ok {
+		return []Match{}
+	}
+
+	scores := make(map[int]float64)
+
+	idx.score(i, 1, scores)
+	delete(scores, i)
+
+	return idx.top(scores, limit)
+}
+
+// Recommend returns up to limit movies suited to a user's ratings and watch
+// history, best first. Each movie the user has interacted with adds its
+// similarity to every candidate, weighted by how much the user liked it, so
+// low ratings push their look-alikes down. Movies the user has already rated
+// or watched are never recommended.
+func (idx *Index) Recommend(signals []*data.UserSignal, limit int) []Match {
+	scores := make(map[int]float64)
+	seen := make(map[int]bool)
+	total := 0.0
+
+	for _, signal := range signals {
+		i, ok := idx.byID[signal.MovieID]
+		if !ok {
+			continue
+		}
+
+		seen[i] = true
+
+		weight := watchedWeight
+		if signal.Rating > 0 {
+			// Ratings run from 1 to 10; map them onto -1 to 1.
+			weight = (float64(signal.Rating) - 5.5) / 4.5
+		}
+
+		idx.score(i, weight, scores)
+		total += math.Abs(weight)
+	}
+
+	for i := range seen {
+		delete(scores, i)
+	}
+
+	// Scaling by the total weight keeps scores between -1 and 1 however long
+	// the user's history is.
+	if total > 0 {
+		for i := range scores {
+			scores[i] /= total
+		}
+	}
+
+	return idx.top(scores, limit)
+}
+
+// score adds weight times the similarity of movie i to every movie sharing a
+// genre or a person with it.
+func (idx *Index) score(i int, weight float64, scores map[int]float64) {
+	type overlap struct {
+		genres int
+		people int
+	}
+
+	movie := idx.movies[i]
+	overlaps := make(map[int]*overlap)
+
+	get := func(j int) *overlap {
+		o, ok := overlaps[j]
+		if !ok {
+			o = &overlap{}
+			overlaps[j] = o
+		}
+		return o
+	}
+
+	for _, genre := range movie.Genres {
+		for _, j := range idx.byGenre[genre] {
+			get(j).genres++
+		}
+	}
+
+	for _, person := range movie.PersonIDs {
+		for _, j := range idx.byPerson[person] {
+			get(j).people++
+		}
+	}
+
+	for j, o := range overlaps {
+		other := idx.movies[j]
+
+		similarity := 0.0
+
+		if union := len(movie.Genres) + len(other.Genres) - o.genres; union > 0 {
+			similarity += genreWeight * float64(o.genres) / float64(union)
+		}
+
+		similarity += creditWeight * math.Min(float64(o.people), creditSaturation) / creditSaturation
+		similarity += eraWeight * proximity(movie.Year, other.Year, eraSpan)
+
+		if movie.Runtime > 0 && other.Runtime > 0 {
+			similarity += runtimeWeight * proximity(movie.Runtime, other.Runtime, runtimeSpan)
+		}
+
+		scores[j] += weight * similarity
+	}
+}
+
+// proximity is 1 for equal values, falling linearly to 0 once they are span
+// apart.
+func proximity(a, b int32, span float64) float64 {
+	distance := math.Abs(float64(a - b))
+	return math.Max(0, 1-distance/span)
+}
+
+// top returns the limit highest positive scores, breaking ties by movie ID so
+// that results are stable.
+func (idx *Index) top(scores map[int]float64, limit int) []Match {
+	matches := []Match{}
+
+	for i, score := range scores {
+		if score <= 0 {
+			continue
+		}
+
+		matches = append(matches, Match{MovieID: idx.movies[i].ID, Score: score})
+	}
+
+	sort.Slice(matches, func(a, b int) bool {
+		if matches[a].Score != matches[b].Score {
+			return matches[a].Score > matches[b].Score
+		}
+		return matches[a].MovieID < matches[b].MovieID
+	})
+
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+
+	return matches
+}
@@ -0,0 +1,156 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// tempPrefix marks files which are still being written, so List skips them.
+const tempPrefix = ".tmp-"
+
+// FileStore is a BlobStore keeping blobs as files under a directory. It also
+// serves them over HTTP, for mounting at the path its URLs point to.
+type FileStore struct {
+	dir     string
+	baseURL string
+}
+
+// NewFileStore returns a FileStore rooted at dir, creating the directory if
+// need be. Blob URLs are baseURL followed by the key.
+func NewFileStore(dir, baseURL string) (*FileStore, error) {
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileStore{dir: dir, baseURL: strings.TrimSuffix(baseURL, "/")}, nil
+}
+
+// path maps a key to its file, refusing keys which would escape the store.
+func (s *FileStore) path(key string) (string, error) {
+	if key == "" || path.Clean("/"+key) != "/"+key || strings.HasPrefix(path.Base(key), tempPrefix) {
+		return "", ErrInvalidKey
+	}
+
+	return filepath.Join(s.dir, filepath.FromSlash(key)), nil
+}
+
+func (s *FileStore) Put(ctx context.Context, key string, body io.Reader, contentType string) error {
+	name, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(name), 0o755)
+	if err != nil {
+		return err
+	}
+
+	// Writing to a temporary file and renaming it into place means readers
+	// never see a half-written blob.
+	tmp, err := os.CreateTemp(filepath.Dir(name), tempPrefix+"*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = io.Copy(tmp, body)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+
+	err = os.Chmod(tmp.Name(), 0o644)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), name)
+}
+
+func (s *FileStore) Delete(ctx context.Context, key string) error {
+	name, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(name)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+func (s *FileStore) List(ctx context.Context, fn func(blob Blob) error) error {
+	return filepath.WalkDir(s.dir, func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), tempPrefix) {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(s.dir, name)
+		if err != nil {
+			return err
+		}
+
+		return fn(Blob{Key: filepath.ToSlash(rel), Size: info.Size(), ModTime: info.ModTime()})
+	})
+}
+
+func (s *FileStore) URL(key string) string {
+	return s.baseURL + "/" + key
+}
+
+// ServeHTTP serves the blob whose key is the request path. Directories are not
+// listed.
+func (s *FileStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name, err := s.path(strings.TrimPrefix(r.URL.Path, "/"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		http.NotFound(w, r)
+		return
+	}
+
+	// Keys are never reused for different content, so blobs can be cached
+	// for as long as clients like.
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+
+	http.ServeContent(w, r, info.Name(), info.ModTime(), f)
+}
@@ -0,0 +1,34 @@
+// Package storage keeps uploaded files, such as movie artwork, in a blob
+// store.
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+var ErrInvalidKey = errors.New("invalid blob key")
+
+// Blob describes a stored file.
+type Blob struct {
+	Key     string
+	Size    int64
+	ModTime time.Time
+}
+
+// BlobStore stores files under slash-separated keys such as
+// "movies/12/poster.jpg". Anything which can put, delete and list objects by
+// key, like the filesystem or an S3-compatible bucket, can back it.
+type BlobStore interface {
+	// Put stores body under key, replacing any blob already there.
+	Put(ctx context.Context, key string, body io.Reader, contentType string) error
+	// Delete removes the blob under key. Deleting a missing blob is not an
+	// error.
+	Delete(ctx context.Context, key string) error
+	// List calls fn for every stored blob, stopping at the first error.
+	List(ctx context.Context, fn func(blob Blob) error) error
+	// URL returns the address clients fetch the blob under key from.
+	URL(key string) string
+}
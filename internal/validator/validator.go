@@ -42,8 +42,8 @@ func Matches(value string, rx *regexp.Regexp) bool {
 	return rx.MatchString(value)
 }
 
-func Unique(values []string) bool {
-	unqiueValues := make(map[string]bool)
+func Unique[T comparable](values []T) bool {
+	unqiueValues := make(map[T]bool)
 
 	for _, value := range values {
 		unqiueValues[value] = true
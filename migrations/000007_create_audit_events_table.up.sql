@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS audit_events (
+  id bigserial PRIMARY KEY,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  user_id bigint REFERENCES users ON DELETE SET NULL,
+  action text NOT NULL,
+  success bool NOT NULL,
+  ip_address text NOT NULL,
+  details jsonb NOT NULL DEFAULT '{}'
+);
+
+CREATE INDEX IF NOT EXISTS audit_events_user_id_idx ON audit_events (user_id);
+CREATE INDEX IF NOT EXISTS audit_events_action_idx ON audit_events (action);
+CREATE INDEX IF NOT EXISTS audit_events_created_at_idx ON audit_events (created_at);
+
+INSERT INTO permissions (code)
+VALUES
+  ('audit:read');
@@ -0,0 +1,3 @@
+ALTER TABLE movies DROP COLUMN IF EXISTS rating_count;
+ALTER TABLE movies DROP COLUMN IF EXISTS average_rating;
+DROP TABLE IF EXISTS reviews;
@@ -0,0 +1,3 @@
+DELETE FROM permissions WHERE code = 'genres:write';
+DROP TABLE IF EXISTS movie_genres;
+DROP TABLE IF EXISTS genres;
@@ -0,0 +1,51 @@
+CREATE TABLE IF NOT EXISTS genres (
+  id bigserial PRIMARY KEY,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  slug text UNIQUE NOT NULL,
+  name text NOT NULL,
+  aliases text[] NOT NULL DEFAULT '{}',
+  version integer NOT NULL DEFAULT 1
+);
+
+CREATE INDEX IF NOT EXISTS genres_aliases_idx ON genres USING GIN (aliases);
+
+CREATE TABLE IF NOT EXISTS movie_genres (
+  movie_id bigint NOT NULL REFERENCES movies ON DELETE CASCADE,
+  genre_id bigint NOT NULL REFERENCES genres ON DELETE RESTRICT,
+  position integer NOT NULL,
+  PRIMARY KEY (movie_id, genre_id)
+);
+
+CREATE INDEX IF NOT EXISTS movie_genres_genre_id_idx ON movie_genres (genre_id);
+
+-- Build the taxonomy from the free-text values already in use. Values which
+-- only differ by case or punctuation ("Sci-Fi", "sci fi") collapse into one
+-- slug; true synonyms have to be merged through the API afterwards.
+INSERT INTO genres (slug, name)
+SELECT slug, initcap(min(raw))
+FROM (
+  SELECT DISTINCT raw, trim(BOTH '-' FROM regexp_replace(lower(raw), '[^a-z0-9]+', '-', 'g')) AS slug
+  FROM movies, unnest(movies.genres) AS raw
+) AS normalized
+WHERE slug <> ''
+GROUP BY slug;
+
+INSERT INTO movie_genres (movie_id, genre_id, position)
+SELECT movies.id, genres.id, min(x.ord)
+FROM movies
+CROSS JOIN unnest(movies.genres) WITH ORDINALITY AS x(raw, ord)
+INNER JOIN genres ON genres.slug = trim(BOTH '-' FROM regexp_replace(lower(x.raw), '[^a-z0-9]+', '-', 'g'))
+GROUP BY movies.id, genres.id;
+
+UPDATE movies
+SET genres = (
+  SELECT array_agg(genres.slug ORDER BY movie_genres.position, genres.slug)
+  FROM movie_genres
+  INNER JOIN genres ON genres.id = movie_genres.genre_id
+  WHERE movie_genres.movie_id = movies.id
+), version = version + 1
+WHERE EXISTS (SELECT 1 FROM movie_genres WHERE movie_genres.movie_id = movies.id);
+
+INSERT INTO permissions (code)
+VALUES
+  ('genres:write');
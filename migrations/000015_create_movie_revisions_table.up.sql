@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS movie_revisions (
+  movie_id bigint NOT NULL REFERENCES movies ON DELETE CASCADE,
+  version integer NOT NULL,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  user_id bigint REFERENCES users ON DELETE SET NULL,
+  snapshot jsonb NOT NULL,
+  diff jsonb NOT NULL DEFAULT '{}',
+  PRIMARY KEY (movie_id, version)
+);
+
+INSERT INTO movie_revisions (movie_id, version, created_at, snapshot)
+SELECT id, version, created_at, jsonb_build_object(
+  'title', title,
+  'year', year,
+  'runtime', runtime::text || ' mins',
+  'genres', genres
+)
+FROM movies
+ON CONFLICT DO NOTHING;
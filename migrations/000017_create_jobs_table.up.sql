@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS jobs (
+  id bigserial PRIMARY KEY,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  updated_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+  kind text NOT NULL,
+  status text NOT NULL DEFAULT 'queued',
+  params jsonb NOT NULL DEFAULT '{}',
+  total integer NOT NULL DEFAULT 0,
+  processed integer NOT NULL DEFAULT 0,
+  inserted integer NOT NULL DEFAULT 0,
+  updated integer NOT NULL DEFAULT 0,
+  failed integer NOT NULL DEFAULT 0,
+  next_item integer NOT NULL DEFAULT 0,
+  errors jsonb NOT NULL DEFAULT '[]',
+  error text NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS jobs_status_idx ON jobs (status, id);
+
+CREATE TABLE IF NOT EXISTS job_items (
+  job_id bigint NOT NULL REFERENCES jobs ON DELETE CASCADE,
+  position integer NOT NULL,
+  payload jsonb NOT NULL,
+  PRIMARY KEY (job_id, position)
+);
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS movie_images (
+  movie_id bigint NOT NULL REFERENCES movies ON DELETE CASCADE,
+  kind text NOT NULL,
+  key text NOT NULL,
+  thumbnail_key text NOT NULL,
+  content_type text NOT NULL,
+  width integer NOT NULL,
+  height integer NOT NULL,
+  updated_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (movie_id, kind)
+);
+
+CREATE INDEX IF NOT EXISTS movie_images_key_idx ON movie_images (key);
+CREATE INDEX IF NOT EXISTS movie_images_thumbnail_key_idx ON movie_images (thumbnail_key);
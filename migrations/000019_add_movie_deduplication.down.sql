@@ -0,0 +1,7 @@
+DELETE FROM permissions WHERE code = 'movies:merge';
+
+DROP INDEX IF EXISTS movies_normalized_title_idx;
+
+CREATE INDEX IF NOT EXISTS movie_external_ids_movie_id_idx ON movie_external_ids (movie_id);
+
+ALTER TABLE movie_external_ids DROP CONSTRAINT IF EXISTS movie_external_ids_movie_source_key;
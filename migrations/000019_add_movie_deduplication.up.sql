@@ -0,0 +1,9 @@
+ALTER TABLE movie_external_ids ADD CONSTRAINT movie_external_ids_movie_source_key UNIQUE (movie_id, source);
+
+DROP INDEX IF EXISTS movie_external_ids_movie_id_idx;
+
+CREATE INDEX IF NOT EXISTS movies_normalized_title_idx ON movies (year, (regexp_replace(lower(title), '[^[:alnum:]]+', '', 'g')));
+
+INSERT INTO permissions (code)
+VALUES
+  ('movies:merge');
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS movie_translations (
+  movie_id bigint NOT NULL REFERENCES movies ON DELETE CASCADE,
+  language text NOT NULL,
+  title text NOT NULL,
+  synopsis text NOT NULL DEFAULT '',
+  updated_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (movie_id, language)
+);
+
+CREATE INDEX IF NOT EXISTS movie_translations_title_trgm_idx ON movie_translations USING GIN (title gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS movie_translations_title_english_idx ON movie_translations USING GIN (to_tsvector('english', title));
+
+CREATE TABLE IF NOT EXISTS movie_releases (
+  movie_id bigint NOT NULL REFERENCES movies ON DELETE CASCADE,
+  country text NOT NULL,
+  release_date date,
+  certification text NOT NULL DEFAULT '',
+  PRIMARY KEY (movie_id, country)
+);
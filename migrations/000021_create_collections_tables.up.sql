@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS collections (
+  id bigserial PRIMARY KEY,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  name text NOT NULL,
+  overview text NOT NULL DEFAULT '',
+  version integer NOT NULL DEFAULT 1
+);
+
+CREATE INDEX IF NOT EXISTS collections_name_idx ON collections USING GIN (to_tsvector('simple', name));
+
+CREATE TABLE IF NOT EXISTS collection_movies (
+  movie_id bigint PRIMARY KEY REFERENCES movies ON DELETE CASCADE,
+  collection_id bigint NOT NULL REFERENCES collections ON DELETE CASCADE,
+  part integer NOT NULL,
+  CONSTRAINT collection_movies_collection_part_key UNIQUE (collection_id, part),
+  CONSTRAINT collection_movies_part_check CHECK (part > 0)
+);
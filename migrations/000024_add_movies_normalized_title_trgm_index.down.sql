@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS movies_normalized_title_trgm_idx;
+
+CREATE INDEX IF NOT EXISTS movies_normalized_title_idx ON movies (year, (regexp_replace(lower(title), '[^[:alnum:]]+', '', 'g')));
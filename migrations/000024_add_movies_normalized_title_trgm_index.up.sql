@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS movies_normalized_title_idx;
+
+CREATE INDEX IF NOT EXISTS movies_normalized_title_trgm_idx ON movies USING GIN ((regexp_replace(lower(title), '[^[:alnum:]]+', '', 'g')) gin_trgm_ops);